@@ -0,0 +1,83 @@
+// Copyright © 2016 David Cuadrado
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/dcu/capn-hook/core"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manages the cache of successful runs",
+	Long: `Hooks with "cache: true" record their successful runs in .git/capn-hook/cache
+and skip the commands whose files didn't change since then.`,
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Removes all the entries of the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		cache, err := core.OpenCache()
+		if err != nil {
+			core.Out.Errorf("Error: %s\n", err)
+			return
+		}
+
+		if err := cache.Clear(); err != nil {
+			core.Out.Errorf("Error: %s\n", err)
+			return
+		}
+
+		core.Out.Infof("Cache cleared\n")
+	},
+}
+
+// cacheStatsCmd represents the cache stats command
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Prints the statistics of the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		cache, err := core.OpenCache()
+		if err != nil {
+			core.Out.Errorf("Error: %s\n", err)
+			return
+		}
+
+		stats, err := cache.Stats()
+		if err != nil {
+			core.Out.Errorf("Error: %s\n", err)
+			return
+		}
+
+		core.Out.Printf("Path: %s\n", cache.Dir)
+		core.Out.Printf("Entries: %d\n", stats.Entries)
+		core.Out.Printf("Size: %d bytes\n", stats.Size)
+		if stats.Entries > 0 {
+			core.Out.Printf("Oldest: %s\n", stats.Oldest.Format(time.RFC3339))
+			core.Out.Printf("Newest: %s\n", stats.Newest.Format(time.RFC3339))
+		}
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	RootCmd.AddCommand(cacheCmd)
+}
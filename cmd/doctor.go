@@ -0,0 +1,65 @@
+// Copyright © 2016 David Cuadrado
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/dcu/capn-hook/core"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks that the tools required by the manifest are installed",
+	Long:  `Checks every tool listed in the "requires" section of the manifest and prints how to install the missing ones.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manifest, err := core.FindManifest()
+		if err != nil {
+			core.Out.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+
+		env, err := manifest.Environment(os.Environ())
+		if err != nil {
+			core.Out.Errorf("Error while loading the environment: %s\n", err)
+			os.Exit(1)
+		}
+
+		failures := manifest.CheckRequirements(env)
+		failed := map[*core.Requirement]bool{}
+		for _, failure := range failures {
+			failed[failure.Requirement] = true
+		}
+
+		for _, requirement := range manifest.Requires {
+			if !failed[requirement] {
+				core.Out.Passed(requirement.Name+" "+requirement.Version, 0)
+			}
+		}
+
+		if len(failures) > 0 {
+			core.PrintRequirementFailures(failures)
+			os.Exit(1)
+		}
+
+		core.Out.Infof("All the required tools are installed\n")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(doctorCmd)
+}
@@ -15,8 +15,6 @@
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/dcu/capn-hook/core"
 	"github.com/mattn/go-zglob"
 	"github.com/spf13/cobra"
@@ -40,7 +38,7 @@ var generatorCmd = &cobra.Command{
 			manifest = core.DefaultManifest()
 		}
 
-		fmt.Printf("Writing default config file to: %s\n", core.DefaultManifestFileName)
+		core.Out.Infof("Writing default config file to: %s\n", core.DefaultManifestFileName)
 		manifest.WriteFile(core.DefaultManifestFileName)
 	},
 }
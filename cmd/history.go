@@ -0,0 +1,137 @@
+// Copyright © 2016 David Cuadrado
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dcu/capn-hook/core"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyHook    *string
+	historyCommand *string
+	historyLimit   *int
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Shows the history of the runs",
+	Long: `Every run of capn-hook is recorded in .git/capn-hook/history.jsonl. The history
+command lists the last runs and the failure rate and duration of every command.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		history, err := core.OpenHistory()
+		if err != nil {
+			core.Out.Errorf("Error: %s\n", err)
+			return
+		}
+
+		entries, err := history.Entries()
+		if err != nil {
+			core.Out.Errorf("Error: %s\n", err)
+			return
+		}
+
+		entries = filterHistory(entries, *historyHook, *historyCommand)
+		if len(entries) == 0 {
+			core.Out.Infof("No runs recorded\n")
+			return
+		}
+
+		printHistoryEntries(entries, *historyLimit)
+		core.Out.Println()
+		printHistoryStats(core.ComputeHistoryStats(entries))
+	},
+}
+
+// filterHistory keeps the commands that match the given hook and command.
+func filterHistory(entries []*core.HistoryEntry, hook string, command string) []*core.HistoryEntry {
+	filtered := []*core.HistoryEntry{}
+	for _, entry := range entries {
+		commands := []*core.HistoryCommand{}
+		for _, historyCommand := range entry.Commands {
+			if hook != "" && entry.HookName != hook && historyCommand.Hook != hook {
+				continue
+			}
+
+			if command != "" && historyCommand.Name != command && !strings.Contains(historyCommand.Command, command) {
+				continue
+			}
+
+			commands = append(commands, historyCommand)
+		}
+
+		if len(commands) > 0 {
+			filteredEntry := *entry
+			filteredEntry.Commands = commands
+			filtered = append(filtered, &filteredEntry)
+		}
+	}
+
+	return filtered
+}
+
+func printHistoryEntries(entries []*core.HistoryEntry, limit int) {
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	writer := tabwriter.NewWriter(core.Out.Writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tHOOK\tCOMMIT\tCOMMANDS\tFAILED\tDURATION")
+	for _, entry := range entries {
+		failed := 0
+		for _, command := range entry.Commands {
+			if command.Status == core.ResultFailed {
+				failed++
+			}
+		}
+
+		commit := entry.Commit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%s\n", entry.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			entry.HookName, commit, len(entry.Commands), failed, formatSeconds(entry.Duration))
+	}
+	writer.Flush()
+}
+
+func printHistoryStats(stats []*core.HistoryStats) {
+	writer := tabwriter.NewWriter(core.Out.Writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "HOOK\tCOMMAND\tRUNS\tFAILURES\tFAILURE RATE\tP50\tP95")
+	for _, stat := range stats {
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%.1f%%\t%s\t%s\n", stat.HookName, stat.Command, stat.Runs, stat.Failures,
+			stat.FailureRate()*100, stat.P50.Round(time.Millisecond), stat.P95.Round(time.Millisecond))
+	}
+	writer.Flush()
+}
+
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	historyHook = historyCmd.Flags().String("hook", "", "Only show the given hook, e.g. pre-commit or a hook name")
+	historyCommand = historyCmd.Flags().String("command", "", "Only show the commands with the given name or containing the given text")
+	historyLimit = historyCmd.Flags().IntP("limit", "l", 20, "Number of runs to list")
+}
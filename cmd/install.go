@@ -15,7 +15,6 @@
 package cmd
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -43,7 +42,7 @@ If you have hooks that you are currently using please back them up before runnin
 	Run: func(cmd *cobra.Command, args []string) {
 		gitDir, err := core.FindGitDir()
 		if err != nil {
-			fmt.Printf("Error: %s\n", err)
+			core.Out.Errorf("Error: %s\n", err)
 			return
 		}
 
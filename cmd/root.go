@@ -21,24 +21,30 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 
+	"github.com/dcu/capn-hook/core"
 	"github.com/spf13/cobra"
 )
 
+var (
+	verbose *bool
+	quiet   *bool
+)
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
-	Use:   "capn-hook",
-	Short: "Manages git hooks",
-	Long:  `capn-hook searches a manifest called "hooks.yml" in your project and runs the command(s) specified in it`,
+	Use:              "capn-hook",
+	Short:            "Manages git hooks",
+	Long:             `capn-hook searches a manifest called "hooks.yml" in your project and runs the command(s) specified in it`,
+	PersistentPreRun: configureOutput,
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		core.Out.Errorf("%s\n", err)
 		os.Exit(-1)
 	}
 }
@@ -51,4 +57,17 @@ func init() {
 	// RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.capn-hook.yaml)")
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
+
+	verbose = RootCmd.PersistentFlags().BoolP("verbose", "v", false, "Print the output of every command while it runs")
+	quiet = RootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only print failures and errors")
+}
+
+// configureOutput sets the level of the output from the --verbose and --quiet flags.
+func configureOutput(cmd *cobra.Command, args []string) {
+	switch {
+	case *quiet:
+		core.Out.Level = core.QuietLevel
+	case *verbose:
+		core.Out.Level = core.VerboseLevel
+	}
 }
@@ -16,54 +16,308 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/dcu/capn-hook/core"
 	"github.com/spf13/cobra"
 )
 
 var (
-	silent *bool
+	silent       *bool
+	allFiles     *bool
+	files        *bool
+	fromRef      *string
+	toRef        *string
+	only         *[]string
+	skip         *[]string
+	dryRun       *bool
+	noCache      *bool
+	report       *string
+	reportFile   *string
+	sarifFile    *string
+	manifestFile *string
 )
 
 // runCmd represents the run command
 var runCmd = &cobra.Command{
-	Use:   "run <hook>",
+	Use:   "run <hook> [args...]",
 	Short: "Runs the specified hook",
-	Long: `Runs the given <hook. A hook can be either:
-  ` + strings.Join(core.SupportedHooks, "\n  "),
+	Long: `Runs the given <hook>. A hook can be either:
+  ` + strings.Join(core.SupportedHooks, "\n  ") + `
+
+By default the hook runs on the modified files. Use --all-files to run it on
+every file tracked by git, --files to run it on the files given as arguments or
+--from-ref and --to-ref to run it on the files changed between two commits.
+
+Hooks and commands with a name or id can be selected with --only and skipped
+with --skip or with the SKIP environment variable, e.g. SKIP=golint,gocyclo.
+
+The pre-receive and update hooks run on the server once for every pushed ref,
+on the files changed by the push extracted from the pushed commit. The manifest
+is the one given with --manifest or the git config ` + core.ServerManifestConfig + `,
+or else the one of the previous commit of the ref, which can only use built-in
+checks. The manifest of the pushed commit is never used.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		manifest, err := core.FindManifest()
+		if *report != "" {
+			if err := core.CheckReportFormat(*report); err != nil {
+				core.Out.Errorf("%s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if *report != "" && *reportFile == "" && !*dryRun {
+			// the report is written to the standard output, the messages go to stderr to keep it parseable
+			level := core.Out.Level
+			core.Out = core.NewOutput(os.Stderr)
+			core.Out.Level = level
+		}
+
+		if len(args) > 0 && core.IsServerHook(args[0]) {
+			runServerHook(args[0], args[1:])
+			return
+		}
+
+		manifest, err := findManifest()
 		if err != nil {
-			println(err.Error())
+			core.Out.Errorf("%s\n", err)
 			return
 		}
 
 		if len(args) == 0 {
 			if !*silent {
-				fmt.Printf("Missing hook name, options are: %v\n", core.SupportedHooks)
+				core.Out.Errorf("Missing hook name, options are: %v\n", core.SupportedHooks)
 			}
 			return
 		}
 
 		hookName := args[0]
-		workingDir := filepath.Dir(manifest.Path)
-		hooks := manifest.Hooks(hookName)
+		options := newRunOptions(manifest, hookName, args[1:], readStdin())
+		options.Files, err = filesToCheck(options)
+		if err != nil {
+			core.Out.Errorf("%s\n", err)
+			os.Exit(1)
+		}
 
-		input := readStdin()
-		for _, hook := range hooks {
-			hook.RunCommands(workingDir, input, args[1:])
+		start := time.Now()
+		results, err := runHooks(manifest, hookName, options)
+		if err != nil {
+			core.Out.Errorf("%s\n", err)
+			os.Exit(1)
 		}
+		printResults(manifest, results, start)
+		writeResults(results)
 
-		if len(hooks) == 0 && !*silent {
-			println("Invalid hook name:", hookName)
+		if core.HasRequiredFailure(results) {
+			os.Exit(1)
 		}
 	},
 }
 
+// findManifest loads the manifest given with --manifest or finds it in the
+// current directory or its parents.
+func findManifest() (*core.Manifest, error) {
+	if *manifestFile == "" {
+		return core.FindManifest()
+	}
+
+	path, err := filepath.Abs(*manifestFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return core.LoadManifest(path)
+}
+
+func newRunOptions(manifest *core.Manifest, hookName string, args []string, input string) *core.RunOptions {
+	return &core.RunOptions{
+		HookName:   hookName,
+		WorkingDir: filepath.Dir(manifest.Path),
+		Input:      input,
+		Args:       args,
+		Only:       *only,
+		Skip:       append(*skip, core.SkipFromEnv()...),
+	}
+}
+
+// runHooks runs the hooks of the manifest with the given name and records them
+// in the history. It returns an error when the hooks can't run.
+func runHooks(manifest *core.Manifest, hookName string, options *core.RunOptions) ([]*core.Result, error) {
+	hooks := manifest.Hooks(hookName)
+
+	var err error
+	options.Env, err = manifest.Environment(os.Environ())
+	if err != nil {
+		return nil, fmt.Errorf("Error while loading the environment: %s", err)
+	}
+
+	// the requirements run their version commands, which a dry run never does
+	if len(hooks) > 0 && !*dryRun {
+		if failures := manifest.CheckRequirements(options.Env); len(failures) > 0 {
+			core.PrintRequirementFailures(failures)
+			return nil, fmt.Errorf("%d required tool(s) missing", len(failures))
+		}
+	}
+
+	if !*noCache {
+		options.Cache, _ = core.OpenCache()
+	}
+
+	for _, step := range append(options.Only, options.Skip...) {
+		if !manifest.HasStep(step) && !*silent {
+			core.Out.Errorf("Unknown hook or command: %s\n", step)
+		}
+	}
+
+	start := time.Now()
+	results := []*core.Result{}
+	for i, hook := range hooks {
+		if *dryRun {
+			printHookHeader(hookName, i, hook)
+			hook.DryRun(options)
+			continue
+		}
+
+		results = append(results, hook.RunCommands(options)...)
+		if core.HasRequiredFailure(results) {
+			break
+		}
+	}
+
+	if len(hooks) == 0 && !*silent {
+		core.Out.Errorf("Invalid hook name: %s\n", hookName)
+	}
+
+	if len(hooks) > 0 && !*dryRun {
+		recordHistory(hookName, start, results)
+	}
+
+	return results, nil
+}
+
+// printResults prints the summary of the results and their problems.
+func printResults(manifest *core.Manifest, results []*core.Result, start time.Time) {
+	if len(results) > 0 && !core.Out.IsQuiet() {
+		threshold, err := manifest.SlowThreshold()
+		if err != nil {
+			core.Out.Errorf("Invalid warn_slower_than: %s\n", err)
+		}
+
+		core.WriteSummary(core.Out.Writer, results, time.Since(start), threshold)
+	}
+
+	core.PrintDiagnostics(core.Diagnostics(results))
+}
+
+// writeResults writes the SARIF file and the report requested by the flags.
+func writeResults(results []*core.Result) {
+	if *sarifFile != "" && !*dryRun {
+		if err := writeSARIF(*sarifFile, results); err != nil {
+			core.Out.Errorf("Error while writing the SARIF file: %s\n", err)
+		}
+	}
+
+	if *report != "" && !*dryRun {
+		if err := writeReport(*report, *reportFile, results); err != nil {
+			core.Out.Errorf("Error while writing the report: %s\n", err)
+		}
+	}
+}
+
+func writeReport(format string, path string, results []*core.Result) error {
+	if path == "" {
+		return core.WriteReport(os.Stdout, format, results)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return core.WriteReport(file, format, results)
+}
+
+func printHookHeader(hookName string, index int, hook *core.Hook) {
+	header := fmt.Sprintf("%s #%d", hookName, index+1)
+	if hook.Identifier() != "" {
+		header += fmt.Sprintf(" (%s)", hook.Identifier())
+	}
+
+	core.Out.Printf("# %s\n", header)
+}
+
+func recordHistory(hookName string, start time.Time, results []*core.Result) {
+	history, err := core.OpenHistory()
+	if err != nil {
+		return
+	}
+
+	if err := history.Append(core.NewHistoryEntry(hookName, start, results)); err != nil {
+		core.Out.Errorf("Error while recording the history: %s\n", err)
+	}
+}
+
+func writeSARIF(path string, results []*core.Result) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return core.WriteSARIF(file, results)
+}
+
+// filesToCheck returns the files selected by the command line flags and sets the
+// diff used to find the changed lines. When --files is given the arguments are
+// consumed as the list of files.
+func filesToCheck(options *core.RunOptions) ([]string, error) {
+	selected := 0
+	for _, set := range []bool{*allFiles, *files, *fromRef != "" || *toRef != ""} {
+		if set {
+			selected++
+		}
+	}
+
+	if selected > 1 {
+		return nil, errors.New("--all-files, --files and --from-ref/--to-ref can't be used together")
+	}
+
+	switch {
+	case *allFiles:
+		return core.FindTrackedFiles()
+	case *files:
+		selectedFiles := options.Args
+		options.Args = []string{}
+
+		return selectedFiles, nil
+	case *fromRef != "" || *toRef != "":
+		if *fromRef == "" {
+			return nil, errors.New("--to-ref requires --from-ref")
+		}
+
+		to := *toRef
+		if to == "" {
+			to = "HEAD"
+		}
+
+		options.DiffArgs = []string{*fromRef, to}
+		return core.FindFilesBetween(*fromRef, to)
+	}
+
+	// the staged content is checked, even if the file was changed or removed in the working tree
+	options.DiffArgs = []string{"--cached"}
+	modifiedFiles := core.FindModifiedFiles()
+
+	var err error
+	options.Source, err = core.NewFileSource(core.IndexRevision, modifiedFiles)
+	return modifiedFiles, err
+}
+
 func readStdin() string {
 	output := ""
 	stat, _ := os.Stdin.Stat()
@@ -93,4 +347,16 @@ func init() {
 	// runCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	silent = runCmd.Flags().BoolP("silent", "s", false, "Do not print errors")
+	allFiles = runCmd.Flags().BoolP("all-files", "a", false, "Run on all the files tracked by git")
+	files = runCmd.Flags().Bool("files", false, "Run on the files given as arguments")
+	fromRef = runCmd.Flags().String("from-ref", "", "Run on the files changed since the given ref")
+	toRef = runCmd.Flags().String("to-ref", "", "Run on the files changed until the given ref (default HEAD)")
+	only = runCmd.Flags().StringSlice("only", []string{}, "Only run the hooks or commands with the given names")
+	skip = runCmd.Flags().StringSlice("skip", []string{}, "Skip the hooks or commands with the given names")
+	report = runCmd.Flags().String("report", "", "Write a report of the results, the format can be either: "+strings.Join(core.ReportFormats, ", "))
+	reportFile = runCmd.Flags().String("report-file", "", "Write the report to the given file instead of the standard output")
+	sarifFile = runCmd.Flags().String("sarif", "", "Write the problems found by the commands with a format to the given SARIF file")
+	noCache = runCmd.Flags().Bool("no-cache", false, "Run the commands even if their result is cached")
+	dryRun = runCmd.Flags().BoolP("dry-run", "n", false, "Print the files and commands of every hook without running them")
+	manifestFile = runCmd.Flags().StringP("manifest", "m", "", "Use the given manifest instead of finding "+core.DefaultManifestFileName)
 }
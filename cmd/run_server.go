@@ -0,0 +1,132 @@
+// Copyright © 2016 David Cuadrado
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dcu/capn-hook/core"
+)
+
+// runServerHook runs the pre-receive or update hook once for every ref updated
+// by the push, on the files changed by the update.
+func runServerHook(hookName string, args []string) {
+	updates := core.ParseUpdateArgs(args)
+	if hookName == core.PreReceiveName {
+		updates = core.ParseReceiveInput(readStdin())
+	}
+
+	manifestPath, err := serverManifestPath()
+	if err != nil {
+		core.Out.Errorf("%s\n", err)
+		os.Exit(1)
+	}
+
+	results := []*core.Result{}
+	for _, update := range updates {
+		tree, err := core.NewServerTree(update)
+		if err != nil {
+			core.Out.Errorf("Error while extracting the files of %s: %s\n", update.Ref, err)
+			os.Exit(1)
+		}
+
+		updateResults, err := runServerUpdate(tree, hookName, args, manifestPath)
+		tree.Close()
+		results = append(results, updateResults...)
+		if err != nil {
+			core.Out.Errorf("%s: %s\n", update.Ref, err)
+			writeResults(results)
+			os.Exit(1)
+		}
+
+		if core.HasRequiredFailure(results) {
+			break
+		}
+	}
+
+	writeResults(results)
+	if core.HasRequiredFailure(results) {
+		os.Exit(1)
+	}
+}
+
+// runServerUpdate runs the hooks in the tree of a ref update. The caller closes
+// the tree, so errors are returned instead of exiting.
+func runServerUpdate(tree *core.ServerTree, hookName string, args []string, manifestPath string) ([]*core.Result, error) {
+	update := tree.Update
+	manifest, err := tree.LoadManifest(manifestPath, hookName)
+	if os.IsNotExist(err) && manifestPath == "" {
+		// the manifest of the server must exist, the one of the repository is optional
+		core.Out.Verbosef("# %s: no %s in the previous commit %s\n", update.Ref, core.DefaultManifestFileName, core.ShortSHA(tree.ManifestRevision()))
+		return []*core.Result{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Error while loading the manifest: %s", err)
+	}
+
+	if len(manifest.Hooks(hookName)) == 0 {
+		return []*core.Result{}, nil
+	}
+
+	if err := tree.Enter(); err != nil {
+		return nil, err
+	}
+
+	core.Out.Infof("# %s %s..%s\n", update.Ref, core.ShortSHA(update.Old), core.ShortSHA(update.New))
+
+	input := fmt.Sprintf("%s %s %s\n", update.Old, update.New, update.Ref)
+	if hookName == core.UpdateName {
+		input = ""
+	}
+
+	options := newRunOptions(manifest, hookName, args, input)
+	options.WorkingDir = tree.Dir
+	options.Files = tree.Files
+	options.RefUpdate = update
+	if !update.IsCreation() && !update.IsDeletion() {
+		options.DiffArgs = []string{update.Old, update.New}
+	}
+
+	start := time.Now()
+	results, err := runHooks(manifest, hookName, options)
+	if err != nil {
+		return nil, err
+	}
+	printResults(manifest, results, start)
+
+	return results, nil
+}
+
+// serverManifestPath returns the absolute path of the manifest given with
+// --manifest or in the git config, or an empty string to use the pushed one.
+func serverManifestPath() (string, error) {
+	path := *manifestFile
+	if path == "" {
+		output, _ := exec.Command("git", "config", "--get", core.ServerManifestConfig).Output()
+		path = strings.TrimSpace(string(output))
+	}
+
+	if path == "" {
+		return "", nil
+	}
+
+	return filepath.Abs(path)
+}
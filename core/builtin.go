@@ -0,0 +1,218 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-zglob"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	builtinChecks = map[string]BuiltinCheck{
+		"trailing-whitespace":   checkTrailingWhitespace,
+		"missing-final-newline": checkMissingFinalNewline,
+		"merge-conflict":        checkMergeConflict,
+		"mixed-line-endings":    checkMixedLineEndings,
+		"byte-order-mark":       checkByteOrderMark,
+		"invalid-utf8":          checkInvalidUTF8,
+		"case-conflict":         checkCaseConflict,
+		"large-files":           checkLargeFiles,
+		"secrets":               checkSecrets,
+		"yaml":                  checkYAML,
+		"json":                  checkJSON,
+		"toml":                  checkTOML,
+		"xml":                   checkXML,
+		"gofmt":                 checkGofmt,
+		"go-parse":              checkGoParse,
+		"go-vet":                checkGoVet,
+		"protected-branch":      checkProtectedBranch,
+		"commit-policy":         checkCommitPolicy,
+		"signed-commits":        checkSignedCommits,
+		"author-identity":       checkAuthorIdentity,
+	}
+
+	// builtinsWithoutFiles are the checks that don't check files. They run
+	// even when there are no files and are never cached.
+	builtinsWithoutFiles = map[string]bool{
+		"protected-branch": true,
+		"commit-policy":    true,
+		"signed-commits":   true,
+		"author-identity":  true,
+	}
+)
+
+// BuiltinCheck is a check implemented in Go. It returns the problems found in
+// the files of the context, and an error if it couldn't check them.
+type BuiltinCheck func(context *BuiltinContext) ([]*Diagnostic, error)
+
+// BuiltinContext is what a built-in check receives when it runs.
+type BuiltinContext struct {
+	Command    *Command
+	HookName   string
+	WorkingDir string
+	Files      []string
+	Args       []string
+	Input      string
+	Env        []string
+	RefUpdate  *RefUpdate
+	Commit     *Commit
+	Source     *FileSource
+}
+
+// FindBuiltin returns the built-in check with the given name.
+func FindBuiltin(name string) (BuiltinCheck, bool) {
+	check, ok := builtinChecks[name]
+	return check, ok
+}
+
+// BuiltinNames returns the names of the built-in checks, sorted.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtinChecks))
+	for name := range builtinChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// DecodeOptions decodes the options of the command into the given value.
+func (context *BuiltinContext) DecodeOptions(options interface{}) error {
+	if len(context.Command.Options) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(context.Command.Options)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.UnmarshalStrict(data, options); err != nil {
+		return fmt.Errorf("invalid options for %s: %s", context.Command.Builtin, err)
+	}
+
+	return nil
+}
+
+// ReadFile returns the content of the file to check: the one of the source of
+// the files, e.g. the index in pre-commit or the commit when the hook runs per
+// commit, or else the one of the working tree.
+func (context *BuiltinContext) ReadFile(file string) ([]byte, error) {
+	return context.Source.ReadFile(file)
+}
+
+// ReadStagedFile returns the content of the file in the index, or in the
+// working tree when it isn't staged. When the files come from a source it
+// returns the content of the source.
+func (context *BuiltinContext) ReadStagedFile(file string) ([]byte, error) {
+	if context.Source != nil {
+		return context.ReadFile(file)
+	}
+
+	content, err := exec.Command("git", "cat-file", "blob", ":"+file).Output()
+	if err != nil {
+		return context.ReadFile(file)
+	}
+
+	return content, nil
+}
+
+// Diagnostic returns a problem of the check on the given line of the file.
+func (context *BuiltinContext) Diagnostic(file string, line int, message string) *Diagnostic {
+	return &Diagnostic{
+		File:     file,
+		Line:     line,
+		Severity: DiagnosticError,
+		Message:  message,
+		Rule:     context.Command.Builtin,
+		Tool:     context.Command.Tool(),
+	}
+}
+
+// matchFilePatterns returns true if the path or the base name of the file
+// matches any of the glob patterns, which can use ** to match directories.
+func matchFilePatterns(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := zglob.Match(pattern, file); ok {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RunBuiltin runs the built-in check of the expanded command and returns its
+// problems and their description as the output.
+func (hook *Hook) RunBuiltin(options *RunOptions, expanded *ExpandedCommand, env []string) ([]*Diagnostic, string, error) {
+	Out.Verbosef("# Running %s\n", expanded.Line)
+	check, ok := FindBuiltin(expanded.Command.Builtin)
+	if !ok {
+		return nil, "", fmt.Errorf("unknown builtin %s, expected one of %s", expanded.Command.Builtin, strings.Join(BuiltinNames(), ", "))
+	}
+
+	context := &BuiltinContext{
+		Command:    expanded.Command,
+		HookName:   options.HookName,
+		WorkingDir: hook.ResolveWorkingDir(options.WorkingDir),
+		Files:      expanded.Files,
+		Args:       options.Args,
+		Input:      options.Input,
+		Env:        env,
+		RefUpdate:  options.RefUpdate,
+		Commit:     options.Commit,
+		Source:     options.Source,
+	}
+
+	diagnostics, err := check(context)
+	if err != nil {
+		return nil, "", err
+	}
+
+	output := diagnosticsOutput(diagnostics)
+	Out.Verbosef("%s", output)
+
+	return diagnostics, output, nil
+}
+
+// diagnosticsOutput returns the diagnostics printed one per line.
+func diagnosticsOutput(diagnostics []*Diagnostic) string {
+	output := &strings.Builder{}
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(output, diagnostic.String())
+	}
+
+	return output.String()
+}
+
+// problemsError is the error of a built-in check that found problems.
+type problemsError struct {
+	count int
+}
+
+func (err *problemsError) Error() string {
+	return fmt.Sprintf("%d problem(s) found", err.count)
+}
+
+// errorDiagnostics returns an error when some of the diagnostics are errors.
+func errorDiagnostics(diagnostics []*Diagnostic) error {
+	count := 0
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == DiagnosticError {
+			count++
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	return &problemsError{count: count}
+}
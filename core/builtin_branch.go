@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+)
+
+var (
+	// AllowProtectedBranchEnvVar is the environment variable that disables the protected-branch check.
+	AllowProtectedBranchEnvVar = "CAPN_HOOK_ALLOW_PROTECTED_BRANCH"
+
+	defaultProtectedBranches = []string{"main", "master"}
+)
+
+type protectedBranchOptions struct {
+	Branches  StringList `yaml:"branches"`
+	AllowPush bool       `yaml:"allow_push"`
+}
+
+// checkProtectedBranch blocks the commits to the protected branches and, in
+// pre-push and in the hooks of the server, the pushes to them. With allow_push
+// only force pushes and deletions of protected branches are blocked.
+func checkProtectedBranch(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &protectedBranchOptions{Branches: defaultProtectedBranches}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	// only true values bypass the check, so CAPN_HOOK_ALLOW_PROTECTED_BRANCH=0 doesn't.
+	if allow, err := strconv.ParseBool(LookupEnv(context.Env, AllowProtectedBranchEnvVar)); err == nil && allow {
+		return []*Diagnostic{}, nil
+	}
+
+	hint := fmt.Sprintf(" (set %s=1 to bypass)", AllowProtectedBranchEnvVar)
+	updates := []*RefUpdate{}
+	switch {
+	case context.RefUpdate != nil:
+		updates = append(updates, context.RefUpdate)
+	case context.HookName == PrePushName:
+		updates = ParsePrePushInput(context.Input)
+	default:
+		branch := CurrentBranch()
+		if branch == "" || !matchAny(options.Branches, branch) {
+			return []*Diagnostic{}, nil
+		}
+
+		return []*Diagnostic{context.Diagnostic("", 0, fmt.Sprintf("committing directly to the protected branch %s is not allowed%s", branch, hint))}, nil
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, update := range updates {
+		branch := update.Branch()
+		if branch == "" || !matchAny(options.Branches, branch) {
+			continue
+		}
+
+		message := ""
+		switch {
+		case update.IsDeletion():
+			message = "deleting the protected branch %s is not allowed"
+		case !update.IsFastForward():
+			message = "force pushing to the protected branch %s is not allowed"
+		case !options.AllowPush:
+			message = "pushing directly to the protected branch %s is not allowed"
+		default:
+			continue
+		}
+
+		diagnostics = append(diagnostics, context.Diagnostic("", 0, fmt.Sprintf(message, branch)+hint))
+	}
+
+	return diagnostics, nil
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// runProtectedBranch runs protected-branch in the hook with the options and the environment.
+func runProtectedBranch(t *testing.T, hookName string, options string, input string, env ...string) []*Diagnostic {
+	t.Helper()
+
+	command := &Command{Builtin: "protected-branch"}
+	if err := yaml.Unmarshal([]byte(options), &command.Options); err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics, err := checkProtectedBranch(&BuiltinContext{Command: command, HookName: hookName, Input: input, Env: env})
+	if err != nil {
+		t.Fatalf("checkProtectedBranch() error = %v", err)
+	}
+
+	return diagnostics
+}
+
+func TestCheckProtectedBranchOnCommit(t *testing.T) {
+	newTestRepository(t)
+	writeTestFile(t, "a.txt", "a\n")
+	runGit(t, "add", "a.txt")
+	runGit(t, "commit", "-q", "-m", "initial")
+
+	tests := []struct {
+		branch  string
+		options string
+		env     []string
+		want    int
+	}{
+		{"main", "", nil, 1},
+		{"master", "", nil, 1},
+		{"feature", "", nil, 0},
+		{"release/1.0", "branches: ['release/*']", nil, 1},
+		{"main", "branches: ['release/*']", nil, 0},
+		{"main", "", []string{"CAPN_HOOK_ALLOW_PROTECTED_BRANCH=1"}, 0},
+		{"main", "", []string{"CAPN_HOOK_ALLOW_PROTECTED_BRANCH=true"}, 0},
+		{"main", "", []string{"CAPN_HOOK_ALLOW_PROTECTED_BRANCH=0"}, 1},
+		{"main", "", []string{"CAPN_HOOK_ALLOW_PROTECTED_BRANCH=false"}, 1},
+		{"main", "", []string{"CAPN_HOOK_ALLOW_PROTECTED_BRANCH=no"}, 1},
+		{"main", "", []string{"CAPN_HOOK_ALLOW_PROTECTED_BRANCH="}, 1},
+	}
+
+	for _, test := range tests {
+		runGit(t, "checkout", "-q", "-B", test.branch)
+		diagnostics := runProtectedBranch(t, PreCommitName, test.options, "", test.env...)
+		if len(diagnostics) != test.want {
+			t.Errorf("checkProtectedBranch() on %s with %q %v = %v, want %d problems", test.branch, test.options, test.env, diagnosticsOutput(diagnostics), test.want)
+		}
+	}
+}
+
+func TestCheckProtectedBranchOnPush(t *testing.T) {
+	newTestRepository(t)
+	writeTestFile(t, "a.txt", "a\n")
+	runGit(t, "add", "a.txt")
+	runGit(t, "commit", "-q", "-m", "first")
+	first := strings.TrimSpace(runGit(t, "rev-parse", "HEAD"))
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "second")
+	second := strings.TrimSpace(runGit(t, "rev-parse", "HEAD"))
+	runGit(t, "checkout", "-q", "-b", "other", first)
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "other")
+	other := strings.TrimSpace(runGit(t, "rev-parse", "HEAD"))
+
+	push := func(ref string, local string, remote string) string {
+		return fmt.Sprintf("refs/heads/x %s %s %s\n", local, ref, remote)
+	}
+
+	tests := []struct {
+		name    string
+		options string
+		input   string
+		want    string
+	}{
+		{"push", "", push("refs/heads/main", second, first), "pushing directly"},
+		{"push allowed", "allow_push: true", push("refs/heads/main", second, first), ""},
+		{"force push", "allow_push: true", push("refs/heads/main", other, second), "force pushing"},
+		{"deletion", "allow_push: true", push("refs/heads/main", ZeroSHA, second), "deleting"},
+		{"creation", "allow_push: true", push("refs/heads/main", second, ZeroSHA), ""},
+		{"other branch", "", push("refs/heads/feature", other, second), ""},
+		{"tag", "", push("refs/tags/main", other, second), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := diagnosticsOutput(runProtectedBranch(t, PrePushName, test.options, test.input))
+			if test.want == "" && output != "" || !strings.Contains(output, test.want) {
+				t.Errorf("checkProtectedBranch() = %q, want %q", output, test.want)
+			}
+		})
+	}
+
+	if diagnostics := runProtectedBranch(t, PrePushName, "", push("refs/heads/main", second, first), "CAPN_HOOK_ALLOW_PROTECTED_BRANCH=1"); len(diagnostics) != 0 {
+		t.Errorf("checkProtectedBranch() = %v, want the push allowed by %s", diagnosticsOutput(diagnostics), AllowProtectedBranchEnvVar)
+	}
+}
+
+func TestParsePrePushInput(t *testing.T) {
+	input := "refs/heads/a 1111 refs/heads/main 2222\n\ninvalid line\nrefs/tags/v1 3333 refs/tags/v1 " + ZeroSHA + "\n"
+
+	want := []*RefUpdate{
+		{LocalRef: "refs/heads/a", New: "1111", Ref: "refs/heads/main", Old: "2222"},
+		{LocalRef: "refs/tags/v1", New: "3333", Ref: "refs/tags/v1", Old: ZeroSHA},
+	}
+	if got := ParsePrePushInput(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePrePushInput() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRefUpdate(t *testing.T) {
+	tests := []struct {
+		update   *RefUpdate
+		branch   string
+		commit   string
+		creation bool
+		deletion bool
+	}{
+		{&RefUpdate{Ref: "refs/heads/main", Old: "1111", New: "2222"}, "main", "2222", false, false},
+		{&RefUpdate{Ref: "refs/heads/release/1.0", Old: ZeroSHA, New: "2222"}, "release/1.0", "2222", true, false},
+		{&RefUpdate{Ref: "refs/heads/main", Old: "1111", New: ZeroSHA}, "main", "1111", false, true},
+		{&RefUpdate{Ref: "refs/tags/v1", Old: "1111", New: "2222"}, "", "2222", false, false},
+	}
+
+	for _, test := range tests {
+		update := test.update
+		if update.Branch() != test.branch || update.Commit() != test.commit || update.IsCreation() != test.creation || update.IsDeletion() != test.deletion {
+			t.Errorf("%+v: Branch() = %q, Commit() = %q, IsCreation() = %v, IsDeletion() = %v", update, update.Branch(), update.Commit(), update.IsCreation(), update.IsDeletion())
+		}
+	}
+}
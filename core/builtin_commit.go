@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	autosquashPrefixes = []string{"fixup!", "squash!", "amend!"}
+	wipSubject         = regexp.MustCompile(`(?i)^(\[wip\]|wip\b)`)
+)
+
+type commitPolicyOptions struct {
+	MessagePattern   string `yaml:"message_pattern"`
+	MaxSubjectLength int    `yaml:"max_subject_length"`
+	RequireSignoff   bool   `yaml:"require_signoff"`
+	AllowFixup       bool   `yaml:"allow_fixup"`
+	AllowWIP         bool   `yaml:"allow_wip"`
+	MaxFiles         int    `yaml:"max_files"`
+}
+
+// checkCommitPolicy checks the message and the size of the commit of a hook
+// run per commit. Merge commits are not limited by max_files.
+func checkCommitPolicy(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &commitPolicyOptions{}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	var messagePattern *regexp.Regexp
+	if options.MessagePattern != "" {
+		var err error
+		if messagePattern, err = regexp.Compile(options.MessagePattern); err != nil {
+			return nil, fmt.Errorf("invalid options for %s: message_pattern: %s", context.Command.Builtin, err)
+		}
+	}
+
+	commit := context.Commit
+	if commit == nil {
+		return nil, fmt.Errorf("%s checks the pushed commits and needs a hook with per_commit: true", context.Command.Builtin)
+	}
+
+	problems := []string{}
+	subject := commit.Subject()
+	if messagePattern != nil && !messagePattern.MatchString(subject) {
+		problems = append(problems, fmt.Sprintf("subject %q doesn't match %s", subject, options.MessagePattern))
+	}
+
+	if options.MaxSubjectLength > 0 && len([]rune(subject)) > options.MaxSubjectLength {
+		problems = append(problems, fmt.Sprintf("subject is %d characters long, more than the maximum of %d", len([]rune(subject)), options.MaxSubjectLength))
+	}
+
+	if options.RequireSignoff {
+		signoff := fmt.Sprintf("Signed-off-by: %s <%s>", commit.AuthorName, commit.AuthorEmail)
+		if !containsString(strings.Split(commit.Message, "\n"), signoff) {
+			problems = append(problems, fmt.Sprintf("missing %q (commit with -s to add it)", signoff))
+		}
+	}
+
+	if !options.AllowFixup {
+		for _, prefix := range autosquashPrefixes {
+			if strings.HasPrefix(subject, prefix) {
+				problems = append(problems, fmt.Sprintf("%s commit, squash it with git rebase -i --autosquash", strings.TrimSuffix(prefix, "!")))
+			}
+		}
+	}
+
+	if !options.AllowWIP && wipSubject.MatchString(subject) {
+		problems = append(problems, "work in progress commit")
+	}
+
+	if options.MaxFiles > 0 && len(commit.Parents) < 2 && len(commit.Files) > options.MaxFiles {
+		problems = append(problems, fmt.Sprintf("commit changes %d files, more than the maximum of %d", len(commit.Files), options.MaxFiles))
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, problem := range problems {
+		diagnostics = append(diagnostics, context.Diagnostic("", 0, fmt.Sprintf("commit %s: %s", commit.ShortSHA(), problem)))
+	}
+
+	return diagnostics, nil
+}
+
+// pushedCommits returns the commits checked by the context: the commit of a hook
+// run per commit, or the ones being pushed in pre-push and in the hooks of the server.
+func (context *BuiltinContext) pushedCommits() ([]*Commit, error) {
+	if context.Commit != nil {
+		return []*Commit{context.Commit}, nil
+	}
+
+	var shas []string
+	var err error
+	switch {
+	case context.RefUpdate != nil:
+		shas, err = CommitsOf([]*RefUpdate{context.RefUpdate}, "--all")
+	case context.HookName == PrePushName:
+		shas, err = CommitsOf(ParsePrePushInput(context.Input), "--remotes")
+	default:
+		return nil, fmt.Errorf("%s checks the pushed commits, it runs in pre-push, pre-receive, update or in a hook with per_commit: true", context.Command.Builtin)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]*Commit, 0, len(shas))
+	for _, sha := range shas {
+		commit, err := LoadCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
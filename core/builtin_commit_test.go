@@ -0,0 +1,161 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// runCommitPolicy runs commit-policy on the commit with the options.
+func runCommitPolicy(t *testing.T, options string, commit *Commit) ([]*Diagnostic, error) {
+	t.Helper()
+
+	command := &Command{Builtin: "commit-policy"}
+	if err := yaml.Unmarshal([]byte(options), &command.Options); err != nil {
+		t.Fatal(err)
+	}
+
+	return checkCommitPolicy(&BuiltinContext{Command: command, HookName: PrePushName, Commit: commit})
+}
+
+func TestCheckCommitPolicy(t *testing.T) {
+	signed := "Add the parser\n\nSigned-off-by: Jane Doe <jane@example.com>\n"
+	tests := []struct {
+		name    string
+		options string
+		message string
+		parents int
+		files   int
+		want    []string
+	}{
+		{name: "no options", message: "Add the parser\n", want: []string{}},
+		{name: "message_pattern", options: "message_pattern: '^(feat|fix): '", message: "fix: the parser\n", want: []string{}},
+		{name: "message_pattern not matched", options: "message_pattern: '^(feat|fix): '", message: "Add the parser\n", want: []string{"doesn't match"}},
+		{name: "max_subject_length", options: "max_subject_length: 14", message: "Add the parser\n\nA longer body line.\n", want: []string{}},
+		{name: "subject too long", options: "max_subject_length: 10", message: "Add the parser\n", want: []string{"14 characters long"}},
+		{name: "subject length in runes", options: "max_subject_length: 5", message: "añadir\n", want: []string{"6 characters long"}},
+		{name: "signoff", options: "require_signoff: true", message: signed, want: []string{}},
+		{name: "missing signoff", options: "require_signoff: true", message: "Add the parser\n", want: []string{"missing \"Signed-off-by: Jane Doe <jane@example.com>\""}},
+		{name: "fixup", message: "fixup! Add the parser\n", want: []string{"fixup commit"}},
+		{name: "squash", message: "squash! Add the parser\n", want: []string{"squash commit"}},
+		{name: "allow_fixup", options: "allow_fixup: true", message: "amend! Add the parser\n", want: []string{}},
+		{name: "wip", message: "WIP parser\n", want: []string{"work in progress"}},
+		{name: "wip tag", message: "[wip] parser\n", want: []string{"work in progress"}},
+		{name: "wip prefix of a word", message: "Wipe the cache\n", want: []string{}},
+		{name: "allow_wip", options: "allow_wip: true", message: "wip parser\n", want: []string{}},
+		{name: "max_files", options: "max_files: 2", message: "Add the parser\n", files: 2, want: []string{}},
+		{name: "too many files", options: "max_files: 2", message: "Add the parser\n", files: 3, want: []string{"changes 3 files"}},
+		{name: "merges are not limited by max_files", options: "max_files: 2", message: "Merge branch 'a'\n", parents: 2, files: 3, want: []string{}},
+		{name: "several problems", options: "max_subject_length: 5", message: "fixup! wip\n", want: []string{"characters long", "fixup commit"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commit := &Commit{SHA: "0123456789abcdef", AuthorName: "Jane Doe", AuthorEmail: "jane@example.com", Message: test.message}
+			for i := 0; i < test.parents; i++ {
+				commit.Parents = append(commit.Parents, "parent")
+			}
+			for i := 0; i < test.files; i++ {
+				commit.Files = append(commit.Files, "file")
+			}
+
+			diagnostics, err := runCommitPolicy(t, test.options, commit)
+			if err != nil {
+				t.Fatalf("checkCommitPolicy() error = %v", err)
+			}
+
+			if len(diagnostics) != len(test.want) {
+				t.Fatalf("checkCommitPolicy() = %q, want %q", diagnosticsOutput(diagnostics), test.want)
+			}
+
+			for i, diagnostic := range diagnostics {
+				if !strings.HasPrefix(diagnostic.Message, "commit 0123456: ") || !strings.Contains(diagnostic.Message, test.want[i]) {
+					t.Errorf("checkCommitPolicy() = %q, want %q", diagnostic.Message, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCommitPolicyErrors(t *testing.T) {
+	if _, err := runCommitPolicy(t, "", nil); err == nil {
+		t.Error("checkCommitPolicy() error = nil, want an error without a commit")
+	}
+
+	if _, err := runCommitPolicy(t, "message_pattern: '('", &Commit{Message: "a\n"}); err == nil {
+		t.Error("checkCommitPolicy() error = nil, want an error for the message_pattern")
+	}
+}
+
+func TestLoadCommit(t *testing.T) {
+	newTestRepository(t)
+	writeTestFile(t, "a.txt", "a\n")
+	writeTestFile(t, "b.txt", "b\n")
+	runGit(t, "add", "-A")
+	runGit(t, "commit", "-q", "-m", "First\n\nBody")
+	first := strings.TrimSpace(runGit(t, "rev-parse", "HEAD"))
+	runGit(t, "rm", "-q", "b.txt")
+	writeTestFile(t, "c.txt", "c\n")
+	runGit(t, "add", "-A")
+	runGit(t, "commit", "-q", "-m", "Second")
+
+	commit, err := LoadCommit("HEAD")
+	if err != nil {
+		t.Fatalf("LoadCommit() error = %v", err)
+	}
+
+	if commit.Subject() != "Second" || commit.AuthorEmail != "test@example.com" || !reflect.DeepEqual(commit.Parents, []string{first}) {
+		t.Errorf("LoadCommit() = %+v", commit)
+	}
+
+	if want := []string{"c.txt"}; !reflect.DeepEqual(commit.Files, want) {
+		t.Errorf("LoadCommit() files = %v, want %v without the deleted ones", commit.Files, want)
+	}
+
+	root, err := LoadCommit(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a.txt", "b.txt"}; root.Message != "First\n\nBody\n" || !reflect.DeepEqual(root.Files, want) {
+		t.Errorf("LoadCommit() of the root commit = %+v", root)
+	}
+}
+
+func TestCheckCaseConflictInACommit(t *testing.T) {
+	newTestRepository(t)
+	writeTestFile(t, "readme.md", "a\n")
+	writeTestFile(t, "README.md", "b\n")
+	runGit(t, "add", "-A")
+	runGit(t, "commit", "-q", "-m", "conflict")
+	commit := strings.TrimSpace(runGit(t, "rev-parse", "HEAD"))
+
+	// the index doesn't have the conflict, the commit does
+	runGit(t, "rm", "-q", "--cached", "readme.md")
+
+	check := func(source *FileSource) []*Diagnostic {
+		diagnostics, err := checkCaseConflict(&BuiltinContext{Command: &Command{Builtin: "case-conflict"}, Files: []string{"README.md"}, Source: source})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return diagnostics
+	}
+
+	source, err := NewFileSource(commit, []string{"README.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := check(source); len(diagnostics) != 1 {
+		t.Errorf("checkCaseConflict() in the commit = %q, want the conflict with readme.md", diagnosticsOutput(diagnostics))
+	}
+
+	index, err := NewFileSource(IndexRevision, []string{"README.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := check(index); len(diagnostics) != 0 {
+		t.Errorf("checkCaseConflict() in the index = %q, want no conflicts", diagnosticsOutput(diagnostics))
+	}
+}
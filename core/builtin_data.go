@@ -0,0 +1,286 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	yamlErrorLine = regexp.MustCompile(`line (\d+): `)
+)
+
+// syntaxError is a problem found while parsing a file.
+type syntaxError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+type yamlOptions struct {
+	AllowDuplicateKeys bool `yaml:"allow_duplicate_keys"`
+	MultiDocument      bool `yaml:"multi_document"`
+}
+
+type jsonOptions struct {
+	AllowDuplicateKeys bool `yaml:"allow_duplicate_keys"`
+}
+
+// checkDataFiles parses the staged content of the files with one of the given
+// extensions and reports their syntax errors. Other files are not checked.
+func (context *BuiltinContext) checkDataFiles(extensions []string, parse func(content []byte) []*syntaxError) ([]*Diagnostic, error) {
+	diagnostics := []*Diagnostic{}
+	for _, file := range context.Files {
+		if !containsString(extensions, strings.ToLower(filepath.Ext(file))) {
+			continue
+		}
+
+		content, err := context.ReadStagedFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, syntaxErr := range parse(content) {
+			diagnostic := context.Diagnostic(file, syntaxErr.Line, syntaxErr.Message)
+			diagnostic.Column = syntaxErr.Column
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	return diagnostics, nil
+}
+
+func checkYAML(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &yamlOptions{}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	return context.checkDataFiles([]string{".yml", ".yaml"}, func(content []byte) []*syntaxError {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.SetStrict(!options.AllowDuplicateKeys)
+
+		for documents := 0; ; documents++ {
+			var value interface{}
+			err := decoder.Decode(&value)
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return yamlSyntaxErrors(err)
+			}
+
+			if documents > 0 && !options.MultiDocument {
+				return []*syntaxError{{Line: secondYAMLDocument(content), Message: "multiple documents in a single file, enable multi_document to allow them"}}
+			}
+		}
+	})
+}
+
+// secondYAMLDocument returns the line of the separator that starts the second document.
+func secondYAMLDocument(content []byte) int {
+	seenContent := false
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(line, "---") && seenContent {
+			return i + 1
+		}
+
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(line, "---") && !strings.HasPrefix(line, "%") {
+			seenContent = true
+		}
+	}
+
+	return 0
+}
+
+// yamlSyntaxErrors returns the errors of the YAML decoder with their line.
+func yamlSyntaxErrors(err error) []*syntaxError {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+
+	syntaxErrors := []*syntaxError{}
+	for _, message := range messages {
+		message = strings.TrimPrefix(message, "yaml: ")
+		syntaxErr := &syntaxError{Message: message}
+		if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+			syntaxErr.Line, _ = strconv.Atoi(match[1])
+			syntaxErr.Message = strings.Replace(message, match[0], "", 1)
+		}
+		syntaxErrors = append(syntaxErrors, syntaxErr)
+	}
+
+	return syntaxErrors
+}
+
+func checkJSON(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &jsonOptions{}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	return context.checkDataFiles([]string{".json"}, func(content []byte) []*syntaxError {
+		var value interface{}
+		if err := json.Unmarshal(content, &value); err != nil {
+			syntaxErr := &syntaxError{Message: err.Error()}
+			if jsonErr, ok := err.(*json.SyntaxError); ok {
+				syntaxErr.Line, syntaxErr.Column = lineAndColumn(content, jsonErr.Offset)
+			}
+			return []*syntaxError{syntaxErr}
+		}
+
+		if options.AllowDuplicateKeys {
+			return nil
+		}
+
+		return jsonDuplicateKeys(content)
+	})
+}
+
+// jsonDuplicateKeys walks the tokens of a valid JSON document and reports the
+// keys repeated in the same object.
+func jsonDuplicateKeys(content []byte) []*syntaxError {
+	// objects have the keys seen so far, arrays have none.
+	type container struct {
+		keys      map[string]bool
+		expectKey bool
+	}
+
+	syntaxErrors := []*syntaxError{}
+	stack := []*container{}
+	valueDone := func() {
+		if len(stack) > 0 && stack[len(stack)-1].keys != nil {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return syntaxErrors
+		}
+
+		if len(stack) > 0 {
+			if top := stack[len(stack)-1]; top.expectKey {
+				if key, ok := token.(string); ok {
+					if top.keys[key] {
+						position := offset + int64(bytes.IndexByte(content[offset:], '"'))
+						line, column := lineAndColumn(content, position)
+						syntaxErrors = append(syntaxErrors, &syntaxError{Line: line, Column: column, Message: fmt.Sprintf("duplicate key %q", key)})
+					}
+					top.keys[key] = true
+					top.expectKey = false
+					continue
+				}
+			}
+		}
+
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, &container{keys: map[string]bool{}, expectKey: true})
+		case json.Delim('['):
+			stack = append(stack, &container{})
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+}
+
+func checkTOML(context *BuiltinContext) ([]*Diagnostic, error) {
+	if err := context.DecodeOptions(&struct{}{}); err != nil {
+		return nil, err
+	}
+
+	return context.checkDataFiles([]string{".toml"}, func(content []byte) []*syntaxError {
+		var value interface{}
+		if _, err := toml.Decode(string(content), &value); err != nil {
+			syntaxErr := &syntaxError{Message: err.Error()}
+			var parseErr toml.ParseError
+			if errors.As(err, &parseErr) {
+				syntaxErr.Line = parseErr.Position.Line
+				syntaxErr.Column = parseErr.Position.Col
+				syntaxErr.Message = parseErr.Message
+			}
+			return []*syntaxError{syntaxErr}
+		}
+
+		return nil
+	})
+}
+
+func checkXML(context *BuiltinContext) ([]*Diagnostic, error) {
+	if err := context.DecodeOptions(&struct{}{}); err != nil {
+		return nil, err
+	}
+
+	return context.checkDataFiles([]string{".xml"}, func(content []byte) []*syntaxError {
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		decoder.Strict = true
+		roots := 0
+		depth := 0
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				if roots == 0 {
+					return []*syntaxError{{Line: 1, Message: "no root element"}}
+				}
+				return nil
+			}
+
+			if err != nil {
+				syntaxErr := &syntaxError{Message: err.Error()}
+				if xmlErr, ok := err.(*xml.SyntaxError); ok {
+					syntaxErr.Line = xmlErr.Line
+					syntaxErr.Message = xmlErr.Msg
+				}
+				return []*syntaxError{syntaxErr}
+			}
+
+			switch token.(type) {
+			case xml.StartElement:
+				if depth == 0 {
+					roots++
+				}
+				depth++
+			case xml.EndElement:
+				depth--
+			}
+
+			if roots > 1 {
+				line, column := lineAndColumn(content, decoder.InputOffset())
+				return []*syntaxError{{Line: line, Column: column, Message: "more than one root element"}}
+			}
+		}
+	})
+}
+
+// lineAndColumn returns the line and column of the byte at the given offset.
+func lineAndColumn(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return line, column
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// diagnosticPositions returns the file:line:column of every diagnostic.
+func diagnosticPositions(diagnostics []*Diagnostic) []string {
+	positions := []string{}
+	for _, diagnostic := range diagnostics {
+		positions = append(positions, fmt.Sprintf("%s:%d:%d", diagnostic.File, diagnostic.Line, diagnostic.Column))
+	}
+
+	return positions
+}
+
+func TestCheckDataFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   string
+		options string
+		file    string
+		content string
+		want    []string
+		message string
+	}{
+		{name: "valid yaml", check: "yaml", file: "a.yml", content: "a: 1\nb: [1, 2]\n", want: []string{}},
+		{name: "invalid yaml", check: "yaml", file: "a.yaml", content: "a: 1\nb: [1, 2\n", want: []string{"a.yaml:2:0"}},
+		{name: "yaml duplicate keys", check: "yaml", file: "a.yml", content: "a: 1\nb: 2\na: 3\n", want: []string{"a.yml:3:0"}, message: `key "a" already set`},
+		{name: "yaml allow_duplicate_keys", check: "yaml", options: "allow_duplicate_keys: true", file: "a.yml", content: "a: 1\na: 3\n", want: []string{}},
+		{name: "yaml multiple documents", check: "yaml", file: "a.yml", content: "# header\n---\na: 1\n---\nb: 2\n", want: []string{"a.yml:4:0"}, message: "multi_document"},
+		{name: "yaml multi_document", check: "yaml", options: "multi_document: true", file: "a.yml", content: "a: 1\n---\nb: 2\n", want: []string{}},
+		{name: "valid json", check: "json", file: "a.json", content: "{\"a\": {\"a\": 1}, \"b\": [{\"a\": 1}, {\"a\": 2}]}\n", want: []string{}},
+		{name: "invalid json", check: "json", file: "a.json", content: "{\n  \"a\": 1,\n}\n", want: []string{"a.json:3:2"}},
+		{name: "json duplicate keys", check: "json", file: "a.json", content: "{\n  \"a\": 1,\n  \"b\": {\"c\": 1, \"c\": 2},\n  \"a\": 3\n}\n", want: []string{"a.json:3:17", "a.json:4:3"}, message: `duplicate key "c"`},
+		{name: "json allow_duplicate_keys", check: "json", options: "allow_duplicate_keys: true", file: "a.json", content: "{\"a\": 1, \"a\": 2}\n", want: []string{}},
+		{name: "valid toml", check: "toml", file: "a.toml", content: "a = 1\n[b]\nc = \"d\"\n", want: []string{}},
+		{name: "invalid toml", check: "toml", file: "a.toml", content: "a = 1\nb = \n", want: []string{"a.toml:2:5"}},
+		{name: "toml duplicate keys", check: "toml", file: "a.toml", content: "a = 1\na = 2\n", want: []string{"a.toml:2:7"}},
+		{name: "valid xml", check: "xml", file: "a.xml", content: "<?xml version=\"1.0\"?>\n<a><b/></a>\n", want: []string{}},
+		{name: "invalid xml", check: "xml", file: "a.xml", content: "<a>\n<b></a>\n", want: []string{"a.xml:2:0"}},
+		{name: "xml without a root", check: "xml", file: "a.xml", content: "<?xml version=\"1.0\"?>\n", want: []string{"a.xml:1:0"}, message: "no root element"},
+		{name: "xml with two roots", check: "xml", file: "a.xml", content: "<a/>\n<b/>\n", want: []string{"a.xml:2:5"}, message: "more than one root element"},
+		{name: "upper case extension", check: "yaml", file: "A.YML", content: "a: [\n", want: []string{"A.YML:1:0"}},
+		{name: "other extensions are ignored", check: "json", file: "a.yml", content: "{\n", want: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diagnostics, err := runBuiltinCheck(t, test.check, test.options, map[string]string{test.file: test.content})
+			if err != nil {
+				t.Fatalf("%s error = %v", test.check, err)
+			}
+
+			if got := diagnosticPositions(diagnostics); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s = %v, want %v", test.check, got, test.want)
+			}
+
+			if test.message != "" && len(diagnostics) > 0 && !strings.Contains(diagnostics[0].Message, test.message) {
+				t.Errorf("%s message = %q, want %q", test.check, diagnostics[0].Message, test.message)
+			}
+		})
+	}
+}
+
+func TestCheckDataFilesWithInvalidOptions(t *testing.T) {
+	for _, check := range []string{"yaml", "json", "toml", "xml"} {
+		if _, err := runBuiltinCheck(t, check, "unknown: true", map[string]string{"a.txt": "x\n"}); err == nil {
+			t.Errorf("%s error = nil, want an error for unknown options", check)
+		}
+	}
+}
+
+func TestLineAndColumn(t *testing.T) {
+	content := []byte("ab\ncd\n")
+	tests := []struct {
+		offset int64
+		line   int
+		column int
+	}{
+		{0, 1, 1},
+		{1, 1, 2},
+		{3, 2, 1},
+		{4, 2, 2},
+		{100, 3, 1},
+	}
+
+	for _, test := range tests {
+		if line, column := lineAndColumn(content, test.offset); line != test.line || column != test.column {
+			t.Errorf("lineAndColumn(%d) = %d:%d, want %d:%d", test.offset, line, column, test.line, test.column)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/analysis/passes/appends"
+	"golang.org/x/tools/go/analysis/passes/asmdecl"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/buildtag"
+	"golang.org/x/tools/go/analysis/passes/cgocall"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/deepequalerrors"
+	"golang.org/x/tools/go/analysis/passes/defers"
+	"golang.org/x/tools/go/analysis/passes/directive"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/loopclosure"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/nilness"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/analysis/passes/shadow"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/sigchanyzer"
+	"golang.org/x/tools/go/analysis/passes/slog"
+	"golang.org/x/tools/go/analysis/passes/sortslice"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/testinggoroutine"
+	"golang.org/x/tools/go/analysis/passes/tests"
+	"golang.org/x/tools/go/analysis/passes/timeformat"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+	"golang.org/x/tools/go/analysis/passes/unusedwrite"
+	"golang.org/x/tools/go/packages"
+)
+
+var (
+	// defaultGoAnalyzers are the analyzers run by go-vet by default, the same as go vet.
+	defaultGoAnalyzers = []*analysis.Analyzer{
+		appends.Analyzer, asmdecl.Analyzer, assign.Analyzer, atomic.Analyzer, bools.Analyzer,
+		buildtag.Analyzer, cgocall.Analyzer, composite.Analyzer, copylock.Analyzer, defers.Analyzer,
+		directive.Analyzer, errorsas.Analyzer, httpresponse.Analyzer, ifaceassert.Analyzer,
+		loopclosure.Analyzer, lostcancel.Analyzer, nilfunc.Analyzer, printf.Analyzer, shift.Analyzer,
+		sigchanyzer.Analyzer, slog.Analyzer, stdmethods.Analyzer, stringintconv.Analyzer,
+		structtag.Analyzer, testinggoroutine.Analyzer, tests.Analyzer, timeformat.Analyzer,
+		unmarshal.Analyzer, unreachable.Analyzer, unsafeptr.Analyzer, unusedresult.Analyzer,
+	}
+
+	// optionalGoAnalyzers are the other analyzers that can be selected in the options of go-vet.
+	optionalGoAnalyzers = []*analysis.Analyzer{
+		deepequalerrors.Analyzer, nilness.Analyzer, shadow.Analyzer, sortslice.Analyzer, unusedwrite.Analyzer,
+	}
+)
+
+type gofmtOptions struct {
+	Fix bool `yaml:"fix"`
+}
+
+type goVetOptions struct {
+	Analyzers []string `yaml:"analyzers"`
+	Disable   []string `yaml:"disable"`
+	Tests     *bool    `yaml:"tests"`
+}
+
+// goFiles returns the Go files of the context.
+func (context *BuiltinContext) goFiles() []string {
+	files := []string{}
+	for _, file := range context.Files {
+		if strings.HasSuffix(file, ".go") {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// checkGofmt reports the files whose staged content isn't formatted with gofmt.
+// With the fix option the files of the working tree are formatted.
+func checkGofmt(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &gofmtOptions{}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, file := range context.goFiles() {
+		content, err := context.ReadStagedFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		formatted, err := format.Source(content)
+		if err != nil {
+			diagnostics = append(diagnostics, context.goParseErrors(file, err)...)
+			continue
+		}
+
+		if bytes.Equal(content, formatted) {
+			continue
+		}
+
+		message := "not formatted with gofmt"
+		if options.Fix {
+			if err := formatFile(file); err != nil {
+				return nil, err
+			}
+			message += ", formatted in the working tree"
+		}
+
+		diagnostics = append(diagnostics, context.Diagnostic(file, firstDifferentLine(content, formatted), message))
+	}
+
+	return diagnostics, nil
+}
+
+// formatFile formats the Go file of the working tree in place.
+func formatFile(file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := format.Source(content)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, formatted, info.Mode().Perm())
+}
+
+// firstDifferentLine returns the first line that differs between both contents.
+func firstDifferentLine(content []byte, other []byte) int {
+	lines := bytes.Split(content, []byte("\n"))
+	otherLines := bytes.Split(other, []byte("\n"))
+	for i := 0; i < len(lines) && i < len(otherLines); i++ {
+		if !bytes.Equal(lines[i], otherLines[i]) {
+			return i + 1
+		}
+	}
+
+	return minInt(len(lines), len(otherLines))
+}
+
+// checkGoParse reports the syntax errors of the staged content of the Go files.
+func checkGoParse(context *BuiltinContext) ([]*Diagnostic, error) {
+	if err := context.DecodeOptions(&struct{}{}); err != nil {
+		return nil, err
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, file := range context.goFiles() {
+		content, err := context.ReadStagedFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := parser.ParseFile(token.NewFileSet(), file, content, parser.AllErrors); err != nil {
+			diagnostics = append(diagnostics, context.goParseErrors(file, err)...)
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// goParseErrors returns the diagnostics of the errors of go/parser.
+func (context *BuiltinContext) goParseErrors(file string, err error) []*Diagnostic {
+	errorList, ok := err.(scanner.ErrorList)
+	if !ok {
+		return []*Diagnostic{context.Diagnostic(file, 0, err.Error())}
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, parseErr := range errorList {
+		diagnostic := context.Diagnostic(file, parseErr.Pos.Line, parseErr.Msg)
+		diagnostic.Column = parseErr.Pos.Column
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
+
+// checkGoVet runs the analyzers on the packages of the Go files and reports
+// the problems found in those files. The packages are loaded from the working
+// tree, in the working dir of the hook.
+func checkGoVet(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &goVetOptions{}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	analyzers, err := selectGoAnalyzers(options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options for %s: %s", context.Command.Builtin, err)
+	}
+
+	files := context.goFiles()
+	if len(files) == 0 || len(analyzers) == 0 {
+		return []*Diagnostic{}, nil
+	}
+
+	// the packages are loaded from the working tree, which is not the content
+	// of the commits checked one by one or of the pushed refs on the server.
+	if context.Commit != nil || context.RefUpdate != nil {
+		diagnostic := context.Diagnostic("", 0, "skipped, the packages are loaded from the working tree and can't be checked for a commit")
+		diagnostic.Severity = DiagnosticWarning
+		return []*Diagnostic{diagnostic}, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := context.WorkingDir
+	if dir == "" {
+		dir = cwd
+	}
+
+	selected := map[string]bool{}
+	patterns := []string{}
+	for _, file := range files {
+		selected[filepath.Clean(file)] = true
+
+		// the files are relative to the root of the repository and the
+		// patterns to the working dir, where the packages are loaded.
+		pattern, err := filepath.Rel(dir, filepath.Join(cwd, filepath.Dir(file)))
+		if err != nil {
+			return nil, err
+		}
+
+		if pattern = "./" + filepath.ToSlash(pattern); !containsString(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Dir: dir, Tests: options.Tests == nil || *options.Tests, Env: context.Env}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := []*Diagnostic{}
+	seen := map[string]bool{}
+	report := func(filename string, line int, column int, message string, rule string) {
+		if relative, err := filepath.Rel(cwd, filename); filename != "" && err == nil {
+			filename = relative
+		}
+
+		// errors without a position, like the ones of go list, are reported too.
+		key := fmt.Sprintf("%s:%d:%d:%s", filename, line, column, message)
+		if (filename != "" && !selected[filename]) || seen[key] {
+			return
+		}
+		seen[key] = true
+
+		diagnostic := context.Diagnostic(filepath.ToSlash(filename), line, message)
+		diagnostic.Column = column
+		diagnostic.Rule = rule
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	wellTyped := []*packages.Package{}
+	for _, pkg := range pkgs {
+		if !pkg.IllTyped {
+			wellTyped = append(wellTyped, pkg)
+			continue
+		}
+
+		for _, pkgErr := range pkg.Errors {
+			position := &Diagnostic{}
+			for _, diagnostic := range parseRegexpDiagnostics(gnuFormat, pkgErr.Pos+": "+pkgErr.Msg) {
+				position = diagnostic
+			}
+			report(position.File, position.Line, position.Column, pkgErr.Msg, "typecheck")
+		}
+	}
+
+	graph, err := checker.Analyze(analyzers, wellTyped, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, action := range graph.Roots {
+		if action.Err != nil {
+			return nil, fmt.Errorf("%s: %s", action.Analyzer.Name, action.Err)
+		}
+
+		for _, analysisDiagnostic := range action.Diagnostics {
+			position := action.Package.Fset.Position(analysisDiagnostic.Pos)
+			report(position.Filename, position.Line, position.Column, analysisDiagnostic.Message, action.Analyzer.Name)
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// selectGoAnalyzers returns the analyzers of the options, or the default ones,
+// without the disabled ones.
+func selectGoAnalyzers(options *goVetOptions) ([]*analysis.Analyzer, error) {
+	analyzers := defaultGoAnalyzers
+	if len(options.Analyzers) > 0 {
+		analyzers = []*analysis.Analyzer{}
+		for _, name := range options.Analyzers {
+			analyzer := findGoAnalyzer(name)
+			if analyzer == nil {
+				return nil, fmt.Errorf("unknown analyzer %s, expected one of %s", name, strings.Join(goAnalyzerNames(), ", "))
+			}
+			analyzers = append(analyzers, analyzer)
+		}
+	}
+
+	selected := []*analysis.Analyzer{}
+	for _, analyzer := range analyzers {
+		if !containsString(options.Disable, analyzer.Name) {
+			selected = append(selected, analyzer)
+		}
+	}
+
+	return selected, nil
+}
+
+func findGoAnalyzer(name string) *analysis.Analyzer {
+	for _, analyzer := range append(append([]*analysis.Analyzer{}, defaultGoAnalyzers...), optionalGoAnalyzers...) {
+		if analyzer.Name == name {
+			return analyzer
+		}
+	}
+
+	return nil
+}
+
+func goAnalyzerNames() []string {
+	names := []string{}
+	for _, analyzer := range append(append([]*analysis.Analyzer{}, defaultGoAnalyzers...), optionalGoAnalyzers...) {
+		names = append(names, analyzer.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// runGoVet runs go-vet on the files of the repository with the context.
+func runGoVet(t *testing.T, context *BuiltinContext) []*Diagnostic {
+	t.Helper()
+
+	context.Command = &Command{Builtin: "go-vet"}
+	context.HookName = PreCommitName
+	context.Env = os.Environ()
+
+	diagnostics, err := checkGoVet(context)
+	if err != nil {
+		t.Fatalf("checkGoVet() error = %v", err)
+	}
+
+	return diagnostics
+}
+
+func TestCheckGoVetInTheWorkingDir(t *testing.T) {
+	dir := newTestRepository(t)
+	writeTestFile(t, "sub/go.mod", "module example.com/sub\n\ngo 1.16\n")
+	writeTestFile(t, "sub/a.go", "package sub\n\nimport \"fmt\"\n\nfunc A() {\n\tfmt.Printf(\"%d\\n\", \"a\")\n}\n")
+	writeTestFile(t, "sub/b.go", "package sub\n\nimport \"fmt\"\n\nfunc B() {\n\tfmt.Printf(\"%d\\n\", \"b\")\n}\n")
+
+	diagnostics := runGoVet(t, &BuiltinContext{WorkingDir: filepath.Join(dir, "sub"), Files: []string{"sub/a.go"}})
+	if got, want := diagnosticRules(diagnostics), []string{"sub/a.go:6:printf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("checkGoVet() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckGoVetReportsLoadErrors(t *testing.T) {
+	dir := newTestRepository(t)
+	writeTestFile(t, "go.mod", "module example.com/repo\n\ngo 1.16\n")
+	writeTestFile(t, "a.go", "package repo\n\nfunc A() int {\n\treturn \"a\"\n}\n")
+	writeTestFile(t, "ignored/a.go", "//go:build ignore\n\npackage ignored\n")
+
+	diagnostics := runGoVet(t, &BuiltinContext{WorkingDir: dir, Files: []string{"a.go", "ignored/a.go"}})
+
+	typecheck, withoutPosition := 0, 0
+	for _, diagnostic := range diagnostics {
+		switch {
+		case diagnostic.File == "a.go" && diagnostic.Line == 4 && diagnostic.Rule == "typecheck":
+			typecheck++
+		case diagnostic.File == "" && diagnostic.Severity == DiagnosticError:
+			withoutPosition++
+		}
+	}
+
+	if typecheck == 0 || withoutPosition == 0 {
+		t.Errorf("checkGoVet() = %v, want the type error and the error of the ignored package", diagnosticsOutput(diagnostics))
+	}
+}
+
+func TestCheckGoVetSkipsCommits(t *testing.T) {
+	for _, context := range []*BuiltinContext{
+		{Files: []string{"a.go"}, Commit: &Commit{SHA: "0123456789abcdef"}},
+		{Files: []string{"a.go"}, RefUpdate: &RefUpdate{Ref: "refs/heads/main"}},
+	} {
+		diagnostics := runGoVet(t, context)
+		if len(diagnostics) != 1 || diagnostics[0].Severity != DiagnosticWarning || errorDiagnostics(diagnostics) != nil {
+			t.Errorf("checkGoVet() = %v, want a warning", diagnosticsOutput(diagnostics))
+		}
+	}
+}
+
+func TestSelectGoAnalyzers(t *testing.T) {
+	analyzers, err := selectGoAnalyzers(&goVetOptions{Analyzers: []string{"printf", "shadow", "nilness"}, Disable: []string{"shadow"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, analyzer := range analyzers {
+		names = append(names, analyzer.Name)
+	}
+	if want := []string{"printf", "nilness"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("selectGoAnalyzers() = %v, want %v", names, want)
+	}
+
+	if _, err := selectGoAnalyzers(&goVetOptions{Analyzers: []string{"unknown"}}); err == nil {
+		t.Error("selectGoAnalyzers() error = nil, want an error for an unknown analyzer")
+	}
+}
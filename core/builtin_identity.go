@@ -0,0 +1,174 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var (
+	identityRoles = []string{"author", "committer"}
+	identLine     = regexp.MustCompile(`^(.*?) ?<([^>]*)>`)
+)
+
+type authorIdentityOptions struct {
+	NamePattern  string     `yaml:"name_pattern"`
+	EmailPattern string     `yaml:"email_pattern"`
+	EmailDomains StringList `yaml:"email_domains"`
+	Check        StringList `yaml:"check"`
+}
+
+// identity is the name and email of the author or the committer of a commit.
+type identity struct {
+	Role  string
+	Name  string
+	Email string
+}
+
+// identityRules are the compiled options of author-identity.
+type identityRules struct {
+	namePattern  *regexp.Regexp
+	emailPattern *regexp.Regexp
+	options      *authorIdentityOptions
+}
+
+// checkAuthorIdentity validates the name and email of the author and the
+// committer: the ones of the commit being created, from git var, or the ones
+// of the pushed commits in pre-push, in the hooks of the server and in hooks
+// run per commit.
+func checkAuthorIdentity(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &authorIdentityOptions{Check: identityRoles}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	rules, err := newIdentityRules(options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options for %s: %s", context.Command.Builtin, err)
+	}
+
+	diagnostics := []*Diagnostic{}
+	if context.Commit == nil && context.RefUpdate == nil && context.HookName != PrePushName {
+		for _, role := range options.Check {
+			ident, err := exec.Command("git", "var", "GIT_"+strings.ToUpper(role)+"_IDENT").Output()
+			if err != nil {
+				message := fmt.Sprintf("%s identity is not configured, set it with git config user.name \"Your Name\" and git config user.email %s", role, rules.exampleEmail())
+				diagnostics = append(diagnostics, context.Diagnostic("", 0, message))
+				continue
+			}
+
+			for _, problem := range rules.check(parseIdentity(role, string(ident))) {
+				diagnostics = append(diagnostics, context.Diagnostic("", 0, problem))
+			}
+		}
+
+		return diagnostics, nil
+	}
+
+	commits, err := context.pushedCommits()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, commit := range commits {
+		identities := map[string]*identity{
+			"author":    {Role: "author", Name: commit.AuthorName, Email: commit.AuthorEmail},
+			"committer": {Role: "committer", Name: commit.CommitterName, Email: commit.CommitterEmail},
+		}
+
+		for _, role := range options.Check {
+			for _, problem := range rules.check(identities[role]) {
+				message := fmt.Sprintf("commit %s %q: %s, then amend it with git commit --amend --no-edit --reset-author", commit.ShortSHA(), commit.Subject(), problem)
+				diagnostics = append(diagnostics, context.Diagnostic("", 0, message))
+			}
+		}
+	}
+
+	return diagnostics, nil
+}
+
+func newIdentityRules(options *authorIdentityOptions) (*identityRules, error) {
+	rules := &identityRules{options: options}
+	for _, role := range options.Check {
+		if !containsString(identityRoles, role) {
+			return nil, fmt.Errorf("check must be %s, not %s", strings.Join(identityRoles, " or "), role)
+		}
+	}
+
+	var err error
+	if options.NamePattern != "" {
+		if rules.namePattern, err = regexp.Compile(options.NamePattern); err != nil {
+			return nil, fmt.Errorf("name_pattern: %s", err)
+		}
+	}
+
+	if options.EmailPattern != "" {
+		if rules.emailPattern, err = regexp.Compile(options.EmailPattern); err != nil {
+			return nil, fmt.Errorf("email_pattern: %s", err)
+		}
+	}
+
+	return rules, nil
+}
+
+// check returns the problems of the identity, each with a hint to fix it.
+func (rules *identityRules) check(ident *identity) []string {
+	options := rules.options
+	nameHint := "set it with git config user.name \"Your Name\""
+	emailHint := "set it with git config user.email " + rules.exampleEmail()
+
+	problems := []string{}
+	switch {
+	case strings.TrimSpace(ident.Name) == "":
+		problems = append(problems, fmt.Sprintf("%s name is empty, %s", ident.Role, nameHint))
+	case rules.namePattern != nil && !rules.namePattern.MatchString(ident.Name):
+		problems = append(problems, fmt.Sprintf("%s name %q doesn't match %s, %s", ident.Role, ident.Name, options.NamePattern, nameHint))
+	}
+
+	domain := ""
+	if at := strings.LastIndex(ident.Email, "@"); at != -1 {
+		domain = strings.ToLower(ident.Email[at+1:])
+	}
+
+	switch {
+	case domain == "":
+		problems = append(problems, fmt.Sprintf("%s email %q is not valid, %s", ident.Role, ident.Email, emailHint))
+	case rules.emailPattern != nil && !rules.emailPattern.MatchString(ident.Email):
+		problems = append(problems, fmt.Sprintf("%s email %s doesn't match %s, %s", ident.Role, ident.Email, options.EmailPattern, emailHint))
+	case len(options.EmailDomains) > 0 && !matchAny(lowerStrings(options.EmailDomains), domain):
+		problems = append(problems, fmt.Sprintf("%s email %s is not in the allowed domains %s, %s", ident.Role, ident.Email, strings.Join(options.EmailDomains, ", "), emailHint))
+	}
+
+	return problems
+}
+
+// exampleEmail returns an email of the first allowed domain for the hints.
+func (rules *identityRules) exampleEmail() string {
+	for _, domain := range rules.options.EmailDomains {
+		if !strings.ContainsAny(domain, "*?[") {
+			return "you@" + domain
+		}
+	}
+
+	return "you@example.com"
+}
+
+// parseIdentity parses the `Name <email> timestamp timezone` output of git var.
+func parseIdentity(role string, ident string) *identity {
+	match := identLine.FindStringSubmatch(strings.TrimSpace(ident))
+	if match == nil {
+		return &identity{Role: role}
+	}
+
+	return &identity{Role: role, Name: match[1], Email: match[2]}
+}
+
+func lowerStrings(values []string) []string {
+	lowered := make([]string, 0, len(values))
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(value))
+	}
+
+	return lowered
+}
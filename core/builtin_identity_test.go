@@ -0,0 +1,157 @@
+package core
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// newTestIdentityRules compiles the options of author-identity written in YAML.
+func newTestIdentityRules(t *testing.T, document string) (*identityRules, error) {
+	t.Helper()
+
+	options := &authorIdentityOptions{Check: identityRoles}
+	if err := yaml.UnmarshalStrict([]byte(document), options); err != nil {
+		t.Fatal(err)
+	}
+
+	return newIdentityRules(options)
+}
+
+func TestParseIdentity(t *testing.T) {
+	tests := []struct {
+		ident string
+		want  *identity
+	}{
+		{"Jane Doe <jane@example.com> 1700000000 +0100\n", &identity{Role: "author", Name: "Jane Doe", Email: "jane@example.com"}},
+		{"<jane@example.com> 1700000000 +0100", &identity{Role: "author", Email: "jane@example.com"}},
+		{"Jane <> 1700000000 +0100", &identity{Role: "author", Name: "Jane"}},
+		{"invalid", &identity{Role: "author"}},
+	}
+
+	for _, test := range tests {
+		if got := parseIdentity("author", test.ident); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseIdentity(%q) = %+v, want %+v", test.ident, got, test.want)
+		}
+	}
+}
+
+func TestNewIdentityRulesWithInvalidOptions(t *testing.T) {
+	for _, document := range []string{"name_pattern: '('", "email_pattern: '['", "check: [reviewer]"} {
+		if _, err := newTestIdentityRules(t, document); err == nil {
+			t.Errorf("newIdentityRules(%q) error = nil, want an error", document)
+		}
+	}
+}
+
+func TestIdentityRulesCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		ident   *identity
+		want    []string
+	}{
+		{"valid", "", &identity{Name: "Jane Doe", Email: "jane@example.com"}, []string{}},
+		{"empty name", "", &identity{Name: " ", Email: "jane@example.com"}, []string{"author name is empty"}},
+		{"invalid email", "", &identity{Name: "Jane", Email: "jane"}, []string{`author email "jane" is not valid`}},
+		{"empty name and email", "", &identity{}, []string{"author name is empty", "author email"}},
+		{"name_pattern", "name_pattern: '^\\S+ \\S+'", &identity{Name: "Jane Doe", Email: "jane@example.com"}, []string{}},
+		{"name_pattern not matched", "name_pattern: '^\\S+ \\S+'", &identity{Name: "jane", Email: "jane@example.com"}, []string{`author name "jane" doesn't match`}},
+		{"email_pattern", "email_pattern: '^[a-z]+@'", &identity{Name: "Jane", Email: "jane@example.com"}, []string{}},
+		{"email_pattern not matched", "email_pattern: '^[a-z]+@'", &identity{Name: "Jane", Email: "jane.doe@example.com"}, []string{"doesn't match ^[a-z]+@"}},
+		{"email_domains", "email_domains: [example.com]", &identity{Name: "Jane", Email: "jane@EXAMPLE.com"}, []string{}},
+		{"email_domains with a pattern", "email_domains: ['*.example.com']", &identity{Name: "Jane", Email: "jane@eu.example.com"}, []string{}},
+		{"other domain", "email_domains: [example.com, '*.example.com']", &identity{Name: "Jane", Email: "jane@gmail.com"}, []string{"not in the allowed domains example.com, *.example.com"}},
+		{"patterns don't match the subdomains", "email_domains: [example.com]", &identity{Name: "Jane", Email: "jane@eu.example.com"}, []string{"not in the allowed domains"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := newTestIdentityRules(t, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.ident.Role = "author"
+			problems := rules.check(test.ident)
+			if len(problems) != len(test.want) {
+				t.Fatalf("check() = %q, want %q", problems, test.want)
+			}
+
+			for i, problem := range problems {
+				if !strings.Contains(problem, test.want[i]) || !strings.Contains(problem, "set it with git config") {
+					t.Errorf("check() = %q, want %q and a hint", problem, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIdentityRulesExampleEmail(t *testing.T) {
+	tests := []struct {
+		options string
+		want    string
+	}{
+		{"", "you@example.com"},
+		{"email_domains: [company.com]", "you@company.com"},
+		{"email_domains: ['*.company.com', company.org]", "you@company.org"},
+		{"email_domains: ['*.company.com']", "you@example.com"},
+	}
+
+	for _, test := range tests {
+		rules, err := newTestIdentityRules(t, test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := rules.exampleEmail(); got != test.want {
+			t.Errorf("exampleEmail() with %q = %q, want %q", test.options, got, test.want)
+		}
+	}
+}
+
+func TestCheckAuthorIdentity(t *testing.T) {
+	newTestRepository(t)
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+		if value, ok := os.LookupEnv(name); ok {
+			os.Unsetenv(name)
+			defer os.Setenv(name, value)
+		}
+	}
+
+	check := func(context *BuiltinContext, options string) []*Diagnostic {
+		context.Command = &Command{Builtin: "author-identity"}
+		if err := yaml.Unmarshal([]byte(options), &context.Command.Options); err != nil {
+			t.Fatal(err)
+		}
+
+		diagnostics, err := checkAuthorIdentity(context)
+		if err != nil {
+			t.Fatalf("checkAuthorIdentity() error = %v", err)
+		}
+		return diagnostics
+	}
+
+	// the identity of the commit being created is the one of the git config
+	if diagnostics := check(&BuiltinContext{HookName: PreCommitName}, "email_domains: [example.com]"); len(diagnostics) != 0 {
+		t.Errorf("checkAuthorIdentity() = %q, want no problems", diagnosticsOutput(diagnostics))
+	}
+
+	if diagnostics := check(&BuiltinContext{HookName: PreCommitName}, "email_domains: [company.com]"); len(diagnostics) != 2 {
+		t.Errorf("checkAuthorIdentity() = %q, want the author and the committer", diagnosticsOutput(diagnostics))
+	}
+
+	commit := &Commit{SHA: "0123456789abcdef", Message: "Add the parser\n", AuthorName: "Jane", AuthorEmail: "jane@company.com", CommitterName: "Bot", CommitterEmail: "bot@ci.local"}
+	diagnostics := check(&BuiltinContext{HookName: PrePushName, Commit: commit}, "email_domains: [company.com]\ncheck: [author, committer]")
+	output := diagnosticsOutput(diagnostics)
+	if len(diagnostics) != 1 || !strings.Contains(output, `commit 0123456 "Add the parser": committer email bot@ci.local`) || !strings.Contains(output, "--reset-author") {
+		t.Errorf("checkAuthorIdentity() = %q, want the committer of the commit", output)
+	}
+
+	if diagnostics := check(&BuiltinContext{HookName: PrePushName, Commit: commit}, "email_domains: [company.com]\ncheck: [author]"); len(diagnostics) != 0 {
+		t.Errorf("checkAuthorIdentity() = %q, want only the author checked", diagnosticsOutput(diagnostics))
+	}
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMaxFileSize = "5MB"
+)
+
+var (
+	sizeExpr  = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([kmgt]?i?b?)$`)
+	sizeUnits = []string{"B", "KB", "MB", "GB", "TB"}
+)
+
+type largeFilesOptions struct {
+	MaxSize       string   `yaml:"max_size"`
+	AllowBinaries bool     `yaml:"allow_binaries"`
+	Allow         []string `yaml:"allow"`
+}
+
+// checkLargeFiles reports the files whose staged size is above the maximum
+// size, and the binary files that are not stored with Git LFS. The sizes and
+// the content come from the source of the files, or else from the index.
+func checkLargeFiles(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &largeFilesOptions{MaxSize: defaultMaxFileSize}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	maxSize, err := parseSize(options.MaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options for %s: %s", context.Command.Builtin, err)
+	}
+
+	files := []string{}
+	for _, file := range context.Files {
+		if context.Source.IsRegular(file) && !matchFilePatterns(options.Allow, file) {
+			files = append(files, file)
+		}
+	}
+
+	sizes := map[string]int64{}
+	if context.Source == nil {
+		sizes = stagedSizes(files)
+	}
+	lfsFiles := lfsTrackedFiles(files)
+
+	diagnostics := []*Diagnostic{}
+	for _, file := range files {
+		size, ok := sizes[file]
+		if !ok {
+			if size, err = context.Source.Size(file); err != nil {
+				return nil, err
+			}
+		}
+
+		if size > maxSize {
+			message := fmt.Sprintf("file is %s, more than the maximum of %s", formatSize(size), formatSize(maxSize))
+			diagnostics = append(diagnostics, context.Diagnostic(file, 0, message))
+			continue
+		}
+
+		if options.AllowBinaries || lfsFiles[file] {
+			continue
+		}
+
+		content, err := context.ReadStagedFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(content) > sniffSize {
+			content = content[:sniffSize]
+		}
+
+		if isBinary(content) {
+			message := fmt.Sprintf("binary file of %s is not stored with Git LFS", formatSize(size))
+			diagnostics = append(diagnostics, context.Diagnostic(file, 0, message))
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// stagedSizes returns the size of the files in the index. Files that are not
+// staged are not included.
+func stagedSizes(files []string) map[string]int64 {
+	sizes := map[string]int64{}
+	if len(files) == 0 {
+		return sizes
+	}
+
+	input := &bytes.Buffer{}
+	for _, file := range files {
+		fmt.Fprintf(input, ":%s\n", file)
+	}
+
+	command := &GitCommand{Args: []string{"cat-file", "--batch-check"}, ProcInput: bytes.NewReader(input.Bytes())}
+	lines := strings.Split(strings.TrimRight(string(command.RunAndGetOutput()), "\n"), "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if i >= len(files) || len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+
+		if size, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			sizes[files[i]] = size
+		}
+	}
+
+	return sizes
+}
+
+// lfsTrackedFiles returns the files that the .gitattributes store with Git LFS.
+func lfsTrackedFiles(files []string) map[string]bool {
+	tracked := map[string]bool{}
+	if len(files) == 0 {
+		return tracked
+	}
+
+	input := strings.Join(files, "\x00") + "\x00"
+	command := &GitCommand{Args: []string{"check-attr", "-z", "--stdin", "filter"}, ProcInput: bytes.NewReader([]byte(input))}
+	fields := strings.Split(string(command.RunAndGetOutput()), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		if fields[i+2] == "lfs" {
+			tracked[fields[i]] = true
+		}
+	}
+
+	return tracked
+}
+
+// parseSize parses a size such as 500KB, 1.5 MB or 1024. Units are powers of 1024.
+func parseSize(size string) (int64, error) {
+	match := sizeExpr.FindStringSubmatch(strings.ToLower(strings.TrimSpace(size)))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if unit := strings.TrimRight(match[2], "ib"); unit != "" {
+		for _, prefix := range "kmgt" {
+			value *= 1024
+			if string(prefix) == unit {
+				break
+			}
+		}
+	}
+
+	return int64(value), nil
+}
+
+// formatSize returns the size in the largest unit that keeps it above 1.
+func formatSize(size int64) string {
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(sizeUnits)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, sizeUnits[unit])
+	}
+
+	return fmt.Sprintf("%.1f %s", value, sizeUnits[unit])
+}
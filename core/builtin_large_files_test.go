@@ -0,0 +1,61 @@
+package core
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size string
+		want int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512b", 512},
+		{"1k", 1024},
+		{"1KB", 1024},
+		{"1KiB", 1024},
+		{"1.5k", 1536},
+		{"2 MB", 2 * 1024 * 1024},
+		{" 1g ", 1024 * 1024 * 1024},
+		{"1tb", 1024 * 1024 * 1024 * 1024},
+	}
+
+	for _, test := range tests {
+		got, err := parseSize(test.size)
+		if err != nil {
+			t.Errorf("parseSize(%q) error = %v", test.size, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("parseSize(%q) = %d, want %d", test.size, got, test.want)
+		}
+	}
+}
+
+func TestParseSizeWithInvalidSize(t *testing.T) {
+	for _, size := range []string{"", "kb", "-1k", "1.2.3", "10pb", "1 k b", "ten"} {
+		if _, err := parseSize(size); err == nil {
+			t.Errorf("parseSize(%q) error = nil, want an error", size)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+	}
+
+	for _, test := range tests {
+		if got := formatSize(test.size); got != test.want {
+			t.Errorf("formatSize(%d) = %q, want %q", test.size, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	// AllowSecretComment suppresses the secrets found on the line that contains it.
+	AllowSecretComment = "capn-hook:allow-secret"
+
+	defaultSecretEntropy   = 4.5
+	defaultSecretMinLength = 20
+	highEntropyRule        = "high-entropy"
+)
+
+var (
+	defaultSecretRules = []*SecretRule{
+		{ID: "aws-access-key-id", Description: "AWS access key id", Regex: `\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`},
+		{ID: "aws-secret-access-key", Description: "AWS secret access key", Regex: `(?i)aws.{0,20}(?:secret|key).{0,20}?['"=:\s]+(?P<secret>[A-Za-z0-9/+=]{40})\b`},
+		{ID: "private-key", Description: "private key", Regex: `-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`},
+		{ID: "slack-token", Description: "Slack token", Regex: `\bxox[baprs]-[0-9A-Za-z-]{10,}`},
+		{ID: "slack-webhook", Description: "Slack webhook", Regex: `https://hooks\.slack\.com/services/T[A-Za-z0-9_]+/B[A-Za-z0-9_]+/[A-Za-z0-9_]+`},
+	}
+
+	secretCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_\-]+`)
+)
+
+// SecretRule is a regular expression that finds secrets. When the expression
+// has a group named secret, only that group is reported.
+type SecretRule struct {
+	ID          string `yaml:"id"`
+	Description string `yaml:"description"`
+	Regex       string `yaml:"regex"`
+
+	expr *regexp.Regexp
+}
+
+type secretsOptions struct {
+	Rules      []*SecretRule `yaml:"rules"`
+	Disable    []string      `yaml:"disable"`
+	Allow      []string      `yaml:"allow"`
+	AllowRegex []string      `yaml:"allow_regex"`
+	Entropy    float64       `yaml:"entropy"`
+	MinLength  int           `yaml:"min_length"`
+}
+
+// checkSecrets reports the secrets found in the staged content of the files.
+func checkSecrets(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &secretsOptions{Entropy: defaultSecretEntropy, MinLength: defaultSecretMinLength}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	rules := []*SecretRule{}
+	for _, rule := range append(append([]*SecretRule{}, defaultSecretRules...), options.Rules...) {
+		if containsString(options.Disable, rule.ID) {
+			continue
+		}
+
+		expr, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret rule %s: %s", rule.ID, err)
+		}
+		rules = append(rules, &SecretRule{ID: rule.ID, Description: rule.Description, Regex: rule.Regex, expr: expr})
+	}
+
+	allowed := []*regexp.Regexp{}
+	for _, allow := range options.AllowRegex {
+		expr, err := regexp.Compile(allow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid options for %s: %s", context.Command.Builtin, err)
+		}
+		allowed = append(allowed, expr)
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, file := range context.Files {
+		if matchFilePatterns(options.Allow, file) {
+			continue
+		}
+
+		content, err := context.ReadStagedFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.IndexByte(content, 0) != -1 {
+			continue // binary
+		}
+
+		for i, line := range strings.Split(string(content), "\n") {
+			if strings.Contains(line, AllowSecretComment) {
+				continue
+			}
+
+			report := func(rule string, description string, secret string, column int) {
+				for _, expr := range allowed {
+					if expr.MatchString(secret) {
+						return
+					}
+				}
+
+				diagnostic := context.Diagnostic(file, i+1, fmt.Sprintf("possible %s %s", description, redactSecret(secret)))
+				diagnostic.Column = column + 1
+				diagnostic.Rule = rule
+				diagnostics = append(diagnostics, diagnostic)
+			}
+
+			found := false
+			for _, rule := range rules {
+				for _, match := range rule.expr.FindAllStringSubmatchIndex(line, -1) {
+					start, end := secretGroup(rule.expr, match)
+					report(rule.ID, valueOrDefault(rule.Description, rule.ID), line[start:end], start)
+					found = true
+				}
+			}
+
+			if found || containsString(options.Disable, highEntropyRule) {
+				continue
+			}
+
+			for _, match := range secretCandidate.FindAllStringIndex(line, -1) {
+				candidate := line[match[0]:match[1]]
+				if len(candidate) >= options.MinLength && shannonEntropy(candidate) >= options.Entropy {
+					report(highEntropyRule, "high-entropy string", candidate, match[0])
+				}
+			}
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// secretGroup returns the bounds of the group named secret, or of the whole match.
+func secretGroup(expr *regexp.Regexp, match []int) (int, int) {
+	if index := expr.SubexpIndex("secret"); index > 0 && match[2*index] >= 0 {
+		return match[2*index], match[2*index+1]
+	}
+
+	return match[0], match[1]
+}
+
+// redactSecret keeps the first characters of the secret so it can be found.
+func redactSecret(secret string) string {
+	visible := minInt(4, len(secret)/4)
+	return secret[:visible] + strings.Repeat("*", minInt(len(secret)-visible, 16))
+}
+
+// shannonEntropy returns the entropy of the string in bits per character.
+func shannonEntropy(value string) float64 {
+	counts := map[rune]int{}
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		frequency := float64(count) / float64(total)
+		entropy -= frequency * math.Log2(frequency)
+	}
+
+	return entropy
+}
+
+func valueOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
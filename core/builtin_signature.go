@@ -0,0 +1,279 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// signatureStatuses describes the %G? statuses of git log that are not a good signature.
+	signatureStatuses = map[string]string{
+		"B": "bad signature",
+		"X": "expired signature",
+		"Y": "signature made by an expired key",
+		"R": "signature made by a revoked key",
+		"E": "signature that can't be checked, the key is missing or gpg.ssh.allowedSignersFile is not set",
+		"N": "not signed",
+	}
+)
+
+type signedCommitsOptions struct {
+	AllowedKeys         StringList `yaml:"allowed_keys"`
+	AllowedSignersFile  string     `yaml:"allowed_signers_file"`
+	AllowUnsignedMerges bool       `yaml:"allow_unsigned_merges"`
+}
+
+// commitSignature is the signature of a commit as verified by git.
+type commitSignature struct {
+	Status      string
+	Key         string
+	Fingerprint string
+	Primary     string
+	Signer      string
+	Output      string
+}
+
+// checkSignedCommits reports the pushed commits that don't have a good signature
+// checked by git verify-commit. With allowed_keys the signature must also be
+// made by one of those keys, even if the key is not trusted by gpg.
+func checkSignedCommits(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &signedCommitsOptions{}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	if err := checkAllowedKeys(options.AllowedKeys); err != nil {
+		return nil, fmt.Errorf("invalid options for %s: %s", context.Command.Builtin, err)
+	}
+
+	signersFile := options.AllowedSignersFile
+	if signersFile != "" && !filepath.IsAbs(signersFile) {
+		// on the server the working dir has the pushed files, which the pusher controls
+		if IsServerHook(context.HookName) || context.RefUpdate != nil {
+			return nil, fmt.Errorf("invalid options for %s: allowed_signers_file %s must be an absolute path on the server, a relative one would be read from the pushed files", context.Command.Builtin, signersFile)
+		}
+		signersFile = filepath.Join(context.WorkingDir, signersFile)
+	}
+
+	if signersFile == "" && !hasGitConfig("gpg.ssh.allowedSignersFile") && len(sshPublicKeys(options.AllowedKeys)) > 0 {
+		// ssh signatures can only be verified with an allowed signers file
+		file, err := writeAllowedSigners(sshPublicKeys(options.AllowedKeys))
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(file)
+		signersFile = file
+	}
+
+	gitArgs := []string{}
+	if signersFile != "" {
+		gitArgs = append(gitArgs, "-c", "gpg.ssh.allowedSignersFile="+signersFile)
+	}
+
+	commits, err := context.pushedCommits()
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, commit := range commits {
+		if options.AllowUnsignedMerges && len(commit.Parents) > 1 {
+			continue
+		}
+
+		signature, err := verifyCommitSignature(gitArgs, commit.SHA)
+		if err != nil {
+			return nil, err
+		}
+
+		problem := ""
+		switch {
+		case signature.Status == "N" && signature.errorMessage() != "":
+			problem = "signature can't be checked: " + signature.errorMessage()
+		case signature.Status != "G" && signature.Status != "U":
+			problem = valueOrDefault(signatureStatuses[signature.Status], "signature can't be verified")
+		case len(options.AllowedKeys) > 0 && !signature.matchesAny(options.AllowedKeys):
+			problem = fmt.Sprintf("signed with %s, which is not an allowed key", signature.description())
+		case len(options.AllowedKeys) == 0 && signature.Status == "U":
+			problem = fmt.Sprintf("signed with %s, which is not trusted", signature.description())
+		default:
+			continue
+		}
+
+		message := fmt.Sprintf("commit %s %q by %s: %s", commit.ShortSHA(), commit.Subject(), commit.AuthorEmail, problem)
+		diagnostics = append(diagnostics, context.Diagnostic("", 0, message))
+		if signature.Output != "" {
+			Out.Verbosef("%s", signature.Output)
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// verifyCommitSignature runs git verify-commit on the commit and returns the
+// status, key and signer of its signature.
+func verifyCommitSignature(gitArgs []string, sha string) (*commitSignature, error) {
+	verifyArgs := append(append([]string{}, gitArgs...), "verify-commit", "--raw", sha)
+	output, _ := exec.Command("git", verifyArgs...).CombinedOutput()
+
+	showArgs := append(append([]string{}, gitArgs...), "show", "-s", "--format=%G?%x00%GK%x00%GF%x00%GP%x00%GS", sha)
+	details, err := exec.Command("git", showArgs...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.SplitN(strings.TrimRight(string(details), "\n"), "\x00", 5)
+	if len(fields) != 5 {
+		return nil, errUnexpectedCommitFormat
+	}
+
+	return &commitSignature{
+		Status:      fields[0],
+		Key:         fields[1],
+		Fingerprint: fields[2],
+		Primary:     fields[3],
+		Signer:      fields[4],
+		Output:      string(output),
+	}, nil
+}
+
+// checkAllowedKeys returns an error for the allowed keys that are not a GPG long
+// key id or fingerprint, an SSH fingerprint or an SSH public key. Short GPG key
+// ids are rejected, they are easy to collide.
+func checkAllowedKeys(allowedKeys []string) error {
+	for _, allowed := range allowedKeys {
+		allowed = strings.TrimSpace(allowed)
+		if _, ok := gpgKeyID(allowed); ok || allowed == "" || strings.HasPrefix(allowed, "SHA256:") || sshFingerprint(allowed) != "" {
+			continue
+		}
+
+		return fmt.Errorf("allowed_keys: %q is not a GPG long key id (16 hex digits) or fingerprint, an SSH fingerprint or an SSH public key", allowed)
+	}
+
+	return nil
+}
+
+// gpgKeyID returns the GPG long key id or fingerprint in upper case and without
+// spaces, or false if the key isn't one.
+func gpgKeyID(key string) (string, bool) {
+	id := strings.ToUpper(strings.ReplaceAll(key, " ", ""))
+	if strings.Trim(id, "0123456789ABCDEF") != "" {
+		return "", false
+	}
+
+	switch len(id) {
+	case 16, 40, 64:
+		return id, true
+	}
+
+	return "", false
+}
+
+// matchesAny returns true if the key, its fingerprint or the fingerprint of its
+// primary key matches one of the allowed keys. GPG long key ids match the end
+// of the fingerprints and are compared ignoring case and spaces. SSH public keys
+// are compared by their SHA256 fingerprint.
+func (signature *commitSignature) matchesAny(allowedKeys []string) bool {
+	for _, allowed := range allowedKeys {
+		allowed = strings.TrimSpace(allowed)
+		gpgID, isGPGID := gpgKeyID(allowed)
+		if allowed == "" {
+			continue
+		}
+
+		if fingerprint := sshFingerprint(allowed); fingerprint != "" {
+			allowed = fingerprint
+		}
+
+		for _, key := range []string{signature.Key, signature.Fingerprint, signature.Primary} {
+			switch {
+			case key == "":
+			case key == allowed:
+				return true
+			case isGPGID && strings.HasSuffix(strings.ToUpper(key), gpgID):
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// errorMessage returns the first error reported by git while verifying the signature.
+func (signature *commitSignature) errorMessage() string {
+	for _, line := range strings.Split(signature.Output, "\n") {
+		if strings.HasPrefix(line, "error: ") {
+			return strings.TrimPrefix(line, "error: ")
+		}
+	}
+
+	return ""
+}
+
+// description returns the key and the signer of the signature.
+func (signature *commitSignature) description() string {
+	key := valueOrDefault(signature.Fingerprint, signature.Key)
+	if signature.Signer == "" {
+		return "the key " + key
+	}
+
+	return fmt.Sprintf("the key %s of %s", key, signature.Signer)
+}
+
+// sshPublicKeys returns the allowed keys that are SSH public keys.
+func sshPublicKeys(allowedKeys []string) []string {
+	keys := []string{}
+	for _, key := range allowedKeys {
+		if sshFingerprint(key) != "" {
+			keys = append(keys, strings.TrimSpace(key))
+		}
+	}
+
+	return keys
+}
+
+// sshFingerprint returns the SHA256 fingerprint of an SSH public key written as
+// `<type> <base64 key> [comment]`, or an empty string if it isn't one.
+func sshFingerprint(key string) string {
+	fields := strings.Fields(key)
+	if len(fields) < 2 || !(strings.HasPrefix(fields[0], "ssh-") || strings.HasPrefix(fields[0], "ecdsa-") || strings.HasPrefix(fields[0], "sk-")) {
+		return ""
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// writeAllowedSigners writes a temporary allowed signers file, which the caller
+// removes, that accepts the keys for any principal.
+func writeAllowedSigners(keys []string) (string, error) {
+	file, err := ioutil.TempFile("", "capn-hook-allowed-signers-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(file, "* %s\n", key); err != nil {
+			return "", err
+		}
+	}
+
+	return file.Name(), nil
+}
+
+func hasGitConfig(name string) bool {
+	output, _ := exec.Command("git", "config", "--get", name).Output()
+	return strings.TrimSpace(string(output)) != ""
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// runSignedCommits runs signed-commits on the commit with the options.
+func runSignedCommits(t *testing.T, context *BuiltinContext, options string) ([]*Diagnostic, error) {
+	t.Helper()
+
+	context.Command = &Command{Builtin: "signed-commits"}
+	if err := yaml.Unmarshal([]byte(options), &context.Command.Options); err != nil {
+		t.Fatal(err)
+	}
+
+	return checkSignedCommits(context)
+}
+
+// newSSHKey generates an SSH key in the directory and returns the path of the
+// private key and the public key.
+func newSSHKey(t *testing.T, dir string, name string) (string, string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+
+	path := filepath.Join(dir, name)
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", name, "-f", path).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %s: %s", err, output)
+	}
+
+	public, err := ioutil.ReadFile(path + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path, strings.TrimSpace(string(public))
+}
+
+func TestGpgKeyID(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"0123456789abcdef", "0123456789ABCDEF", true},
+		{"0123 4567 89AB CDEF", "0123456789ABCDEF", true},
+		{"0123456789ABCDEF0123456789ABCDEF01234567", "0123456789ABCDEF0123456789ABCDEF01234567", true},
+		{"89ABCDEF", "", false},
+		{"0123456789abcdeg", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		if got, ok := gpgKeyID(test.key); got != test.want || ok != test.ok {
+			t.Errorf("gpgKeyID(%q) = %q, %v, want %q, %v", test.key, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestCheckAllowedKeys(t *testing.T) {
+	valid := [][]string{
+		nil,
+		{"0123456789ABCDEF", "0123456789ABCDEF0123456789ABCDEF01234567"},
+		{"SHA256:2Z3hHBP6eBflzANm3gCvV5Ph6GZ3S4bA6wx54fD9U4M"},
+		{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIG5ZNq1zG1iL+aC4n4pWmjrXdiRsdpeGe4v7IqJ8VxO1 jane"},
+	}
+	for _, keys := range valid {
+		if err := checkAllowedKeys(keys); err != nil {
+			t.Errorf("checkAllowedKeys(%q) error = %v", keys, err)
+		}
+	}
+
+	invalid := [][]string{
+		{"89ABCDEF"},
+		{"jane@example.com"},
+		{"ssh-ed25519 not-base64!"},
+	}
+	for _, keys := range invalid {
+		if err := checkAllowedKeys(keys); err == nil {
+			t.Errorf("checkAllowedKeys(%q) error = nil, want an error", keys)
+		}
+	}
+}
+
+func TestSSHFingerprint(t *testing.T) {
+	_, public := newSSHKey(t, t.TempDir(), "jane")
+
+	output, err := exec.Command("sh", "-c", "echo \"$0\" | ssh-keygen -l -E sha256 -f -", public).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := sshFingerprint(public), strings.Fields(string(output))[1]; got != want {
+		t.Errorf("sshFingerprint() = %q, want %q", got, want)
+	}
+
+	for _, key := range []string{"", "ssh-ed25519", "rsa AAAA", "ssh-ed25519 %%%"} {
+		if got := sshFingerprint(key); got != "" {
+			t.Errorf("sshFingerprint(%q) = %q, want nothing", key, got)
+		}
+	}
+}
+
+func TestCommitSignatureMatchesAny(t *testing.T) {
+	gpg := &commitSignature{Key: "89ABCDEF01234567", Fingerprint: "0123456789ABCDEF0123456789ABCDEF01234567", Primary: "FEDCBA9876543210FEDCBA9876543210FEDCBA98"}
+	ssh := &commitSignature{Key: "SHA256:2Z3hHBP6eBflzANm3gCvV5Ph6GZ3S4bA6wx54fD9U4M"}
+
+	tests := []struct {
+		signature *commitSignature
+		allowed   []string
+		want      bool
+	}{
+		{gpg, []string{"89ABCDEF01234567"}, true},
+		{gpg, []string{"89abcdef 01234567"}, true},
+		{gpg, []string{"0123456789ABCDEF0123456789ABCDEF01234567"}, true},
+		{gpg, []string{"FEDCBA9876543210"}, false},
+		{gpg, []string{"FEDCBA9876543210FEDCBA9876543210FEDCBA98"}, true},
+		{gpg, []string{"0000000000000000", ""}, false},
+		{ssh, []string{"SHA256:2Z3hHBP6eBflzANm3gCvV5Ph6GZ3S4bA6wx54fD9U4M"}, true},
+		{ssh, []string{"SHA256:other"}, false},
+		{&commitSignature{}, []string{"0000000000000000"}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.signature.matchesAny(test.allowed); got != test.want {
+			t.Errorf("matchesAny(%q) of %+v = %v, want %v", test.allowed, test.signature, got, test.want)
+		}
+	}
+}
+
+func TestCheckSignedCommitsRejectsRelativeSignersFileOnTheServer(t *testing.T) {
+	update := &RefUpdate{Ref: "refs/heads/main", Old: ZeroSHA, New: ZeroSHA}
+	for _, hookName := range []string{PreReceiveName, UpdateName} {
+		_, err := runSignedCommits(t, &BuiltinContext{HookName: hookName, WorkingDir: t.TempDir(), RefUpdate: update}, "allowed_signers_file: allowed_signers")
+		if err == nil || !strings.Contains(err.Error(), "absolute path") {
+			t.Errorf("checkSignedCommits() in %s error = %v, want an error for the relative allowed_signers_file", hookName, err)
+		}
+	}
+}
+
+func TestCheckSignedCommitsWithSSH(t *testing.T) {
+	dir := newTestRepository(t)
+	keys := t.TempDir()
+	key, public := newSSHKey(t, keys, "jane")
+	_, otherPublic := newSSHKey(t, keys, "john")
+
+	runGit(t, "config", "gpg.format", "ssh")
+	runGit(t, "config", "user.signingkey", key)
+	writeTestFile(t, "allowed_signers", "test@example.com "+public+"\n")
+	runGit(t, "add", "-A")
+	runGit(t, "commit", "-q", "-S", "-m", "signed")
+	signed, err := LoadCommit("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "unsigned")
+	unsigned, err := LoadCommit("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		commit  *Commit
+		options string
+		want    string
+	}{
+		{"relative allowed_signers_file", signed, "allowed_signers_file: allowed_signers", ""},
+		{"allowed key", signed, "allowed_keys: ['" + public + "']", ""},
+		{"other allowed key", signed, "allowed_keys: ['" + otherPublic + "']", "not an allowed key"},
+		{"unsigned", unsigned, "allowed_signers_file: allowed_signers", "not signed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diagnostics, err := runSignedCommits(t, &BuiltinContext{HookName: PrePushName, WorkingDir: dir, Commit: test.commit}, test.options)
+			if err != nil {
+				t.Fatalf("checkSignedCommits() error = %v", err)
+			}
+
+			output := diagnosticsOutput(diagnostics)
+			if test.want == "" && output != "" || !strings.Contains(output, test.want) {
+				t.Errorf("checkSignedCommits() = %q, want %q", output, test.want)
+			}
+		})
+	}
+}
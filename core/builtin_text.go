@@ -0,0 +1,255 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM = []byte{0xef, 0xbb, 0xbf}
+)
+
+type lineEndingsOptions struct {
+	Expect string `yaml:"expect"`
+}
+
+// textFileCheck checks the lines of a text file. The lines keep their \r but
+// not their \n.
+type textFileCheck func(file string, content []byte, lines [][]byte) []*Diagnostic
+
+// checkTextFiles runs the check on the regular files of the context that don't
+// look binary.
+func (context *BuiltinContext) checkTextFiles(check textFileCheck) ([]*Diagnostic, error) {
+	diagnostics := []*Diagnostic{}
+	for _, file := range context.Files {
+		if !context.Source.IsRegular(file) {
+			continue
+		}
+
+		content, err := context.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.IndexByte(content, 0) != -1 {
+			continue // binary
+		}
+
+		lines := bytes.Split(content, []byte("\n"))
+		if len(lines[len(lines)-1]) == 0 {
+			lines = lines[:len(lines)-1]
+		}
+
+		diagnostics = append(diagnostics, check(file, content, lines)...)
+	}
+
+	return diagnostics, nil
+}
+
+func checkTrailingWhitespace(context *BuiltinContext) ([]*Diagnostic, error) {
+	return context.checkTextFiles(func(file string, content []byte, lines [][]byte) []*Diagnostic {
+		diagnostics := []*Diagnostic{}
+		for i, line := range lines {
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			trimmed := bytes.TrimRight(line, " \t")
+			if len(trimmed) != len(line) {
+				diagnostic := context.Diagnostic(file, i+1, "trailing whitespace")
+				diagnostic.Column = utf8.RuneCount(trimmed) + 1
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+
+		return diagnostics
+	})
+}
+
+func checkMissingFinalNewline(context *BuiltinContext) ([]*Diagnostic, error) {
+	return context.checkTextFiles(func(file string, content []byte, lines [][]byte) []*Diagnostic {
+		if len(content) == 0 || content[len(content)-1] == '\n' {
+			return nil
+		}
+
+		return []*Diagnostic{context.Diagnostic(file, len(lines), "missing newline at end of file")}
+	})
+}
+
+func checkMergeConflict(context *BuiltinContext) ([]*Diagnostic, error) {
+	return context.checkTextFiles(func(file string, content []byte, lines [][]byte) []*Diagnostic {
+		diagnostics := []*Diagnostic{}
+		inConflict := false
+		for i, line := range lines {
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			switch {
+			case isConflictMarker(line, "<<<<<<<"):
+				inConflict = true
+			case isConflictMarker(line, ">>>>>>>"):
+				inConflict = false
+			case inConflict && (string(line) == "=======" || isConflictMarker(line, "|||||||")):
+			default:
+				continue
+			}
+
+			diagnostics = append(diagnostics, context.Diagnostic(file, i+1, fmt.Sprintf("merge conflict marker %s", line[:7])))
+		}
+
+		return diagnostics
+	})
+}
+
+func isConflictMarker(line []byte, marker string) bool {
+	return bytes.HasPrefix(line, []byte(marker)) && (len(line) == len(marker) || line[len(marker)] == ' ')
+}
+
+func checkMixedLineEndings(context *BuiltinContext) ([]*Diagnostic, error) {
+	options := &lineEndingsOptions{}
+	if err := context.DecodeOptions(options); err != nil {
+		return nil, err
+	}
+
+	expect := strings.ToLower(options.Expect)
+	if expect != "" && expect != "lf" && expect != "crlf" {
+		return nil, fmt.Errorf("invalid options for %s: expect must be lf or crlf", context.Command.Builtin)
+	}
+
+	return context.checkTextFiles(func(file string, content []byte, lines [][]byte) []*Diagnostic {
+		firstLine := map[string]int{}
+		count := map[string]int{}
+		for i, line := range lines {
+			if i == len(lines)-1 && content[len(content)-1] != '\n' {
+				break // the last line has no line ending
+			}
+
+			ending := "lf"
+			if bytes.HasSuffix(line, []byte("\r")) {
+				ending = "crlf"
+			}
+
+			if count[ending] == 0 {
+				firstLine[ending] = i + 1
+			}
+			count[ending]++
+		}
+
+		wrong := ""
+		switch {
+		case expect == "lf" && count["crlf"] > 0:
+			wrong = "crlf"
+		case expect == "crlf" && count["lf"] > 0:
+			wrong = "lf"
+		case expect == "" && count["lf"] > 0 && count["crlf"] > 0:
+			wrong = "crlf"
+			if count["lf"] < count["crlf"] {
+				wrong = "lf"
+			}
+		default:
+			return nil
+		}
+
+		message := fmt.Sprintf("mixed line endings: %d LF and %d CRLF", count["lf"], count["crlf"])
+		if expect != "" {
+			message = fmt.Sprintf("expected %s line endings, found %d %s", strings.ToUpper(expect), count[wrong], strings.ToUpper(wrong))
+		}
+
+		return []*Diagnostic{context.Diagnostic(file, firstLine[wrong], message)}
+	})
+}
+
+func checkByteOrderMark(context *BuiltinContext) ([]*Diagnostic, error) {
+	return context.checkTextFiles(func(file string, content []byte, lines [][]byte) []*Diagnostic {
+		if !bytes.HasPrefix(content, utf8BOM) {
+			return nil
+		}
+
+		return []*Diagnostic{context.Diagnostic(file, 1, "byte-order mark at the start of the file")}
+	})
+}
+
+func checkInvalidUTF8(context *BuiltinContext) ([]*Diagnostic, error) {
+	return context.checkTextFiles(func(file string, content []byte, lines [][]byte) []*Diagnostic {
+		diagnostics := []*Diagnostic{}
+		for i, line := range lines {
+			if utf8.Valid(line) {
+				continue
+			}
+
+			column := 1
+			for len(line) > 0 {
+				r, size := utf8.DecodeRune(line)
+				if r == utf8.RuneError && size <= 1 {
+					break
+				}
+				line = line[size:]
+				column++
+			}
+
+			diagnostic := context.Diagnostic(file, i+1, "invalid UTF-8")
+			diagnostic.Column = column
+			diagnostics = append(diagnostics, diagnostic)
+		}
+
+		return diagnostics
+	})
+}
+
+// checkCaseConflict reports the files whose path, or the path of one of their
+// directories, only differs in case from another file of the repository, or of
+// the commit when the files are checked in a commit.
+func checkCaseConflict(context *BuiltinContext) ([]*Diagnostic, error) {
+	paths := map[string][]string{}
+	seen := map[string]bool{}
+	addPath := func(file string) {
+		for ; file != "." && file != "/" && file != ""; file = path.Dir(file) {
+			if seen[file] {
+				return
+			}
+			seen[file] = true
+
+			lower := strings.ToLower(file)
+			paths[lower] = append(paths[lower], file)
+		}
+	}
+
+	var trackedFiles []string
+	var err error
+	switch {
+	case context.Source != nil:
+		trackedFiles, err = context.Source.AllFiles()
+	case context.RefUpdate != nil:
+		trackedFiles, err = FindFilesIn(context.RefUpdate.New)
+	default:
+		trackedFiles, err = FindTrackedFiles()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range trackedFiles {
+		addPath(file)
+	}
+	for _, file := range context.Files {
+		addPath(file)
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, file := range context.Files {
+		for current := file; current != "." && current != "/" && current != ""; current = path.Dir(current) {
+			conflicts := []string{}
+			for _, other := range paths[strings.ToLower(current)] {
+				if other != current {
+					conflicts = append(conflicts, other)
+				}
+			}
+
+			if len(conflicts) > 0 {
+				message := fmt.Sprintf("%s only differs in case from %s", current, strings.Join(conflicts, ", "))
+				diagnostics = append(diagnostics, context.Diagnostic(file, 0, message))
+				break
+			}
+		}
+	}
+
+	return diagnostics, nil
+}
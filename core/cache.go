@@ -0,0 +1,201 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	errUnexpectedHashes = errors.New("git hash-object returned an unexpected number of hashes")
+)
+
+// Cache records the successful runs of the commands of the hooks with cache enabled.
+type Cache struct {
+	Dir string
+}
+
+// CacheEntry is a successful run stored in the cache.
+type CacheEntry struct {
+	Command   string    `json:"command"`
+	Files     []string  `json:"files"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CacheStats are the statistics of the cache.
+type CacheStats struct {
+	Entries int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// OpenCache returns the cache stored in the GITDIR.
+func OpenCache() (*Cache, error) {
+	gitDir, err := FindGitDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{Dir: filepath.Join(gitDir, "capn-hook", "cache")}, nil
+}
+
+// Key returns the key of the given command in the cache. The key depends on the
+// command line, the variables set by the manifest, the hook and the command in
+// its environment, the output of the version command, run with that
+// environment, and the contents of the files: the ones of the source when there
+// is one, which the built-in checks read, and the ones of the working tree,
+// which the other commands read. An empty key is returned when it can't be
+// computed.
+func (cache *Cache) Key(workingDir string, expanded *ExpandedCommand, env []string, source *FileSource) string {
+	hash := sha256.New()
+	hash.Write([]byte(expanded.Line))
+	hash.Write([]byte{0})
+
+	if len(expanded.Command.Options) > 0 {
+		options, err := yaml.Marshal(expanded.Command.Options)
+		if err != nil {
+			return ""
+		}
+		hash.Write(options)
+	}
+	hash.Write([]byte{0})
+
+	for _, entry := range addedEnv(env) {
+		hash.Write([]byte(entry))
+		hash.Write([]byte{0})
+	}
+	hash.Write([]byte{0})
+
+	if expanded.Command.VersionCmd != "" {
+		cmdParts := strings.Split(expanded.Command.VersionCmd, " ")
+		cmd := exec.Command(lookPath(cmdParts[0], env), cmdParts[1:]...)
+		cmd.Dir = workingDir
+		cmd.Env = env
+
+		version, err := cmd.CombinedOutput()
+		if err != nil {
+			return ""
+		}
+		hash.Write(version)
+	}
+	hash.Write([]byte{0})
+
+	blobs, err := source.objectIDs(expanded.Files)
+	if err != nil {
+		return ""
+	}
+
+	if source == nil || expanded.Command.Builtin == "" {
+		workingTreeBlobs, err := hashFiles(expanded.Files)
+		if err != nil {
+			return ""
+		}
+		blobs = append(blobs, workingTreeBlobs...)
+	}
+
+	for _, blob := range blobs {
+		hash.Write([]byte(blob))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Has returns true if the given key is in the cache.
+func (cache *Cache) Has(key string) bool {
+	_, err := os.Stat(cache.entryPath(key))
+	return err == nil
+}
+
+// Store records the successful run of a command.
+func (cache *Cache) Store(key string, expanded *ExpandedCommand) error {
+	err := os.MkdirAll(cache.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	entry := &CacheEntry{Command: expanded.Line, Files: expanded.Files, CreatedAt: time.Now()}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(cache.entryPath(key), data, 0644)
+}
+
+// Clear removes all the entries of the cache.
+func (cache *Cache) Clear() error {
+	return os.RemoveAll(cache.Dir)
+}
+
+// Stats returns the statistics of the cache.
+func (cache *Cache) Stats() (*CacheStats, error) {
+	stats := &CacheStats{}
+
+	infos, err := ioutil.ReadDir(cache.Dir)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+
+		stats.Entries++
+		stats.Size += info.Size()
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		if info.ModTime().After(stats.Newest) {
+			stats.Newest = info.ModTime()
+		}
+	}
+
+	return stats, nil
+}
+
+func (cache *Cache) entryPath(key string) string {
+	return filepath.Join(cache.Dir, key)
+}
+
+// hashFiles returns the git blob hash of each file, sorted by file name.
+func hashFiles(files []string) ([]string, error) {
+	if len(files) == 0 {
+		return []string{}, nil
+	}
+
+	sortedFiles := append([]string{}, files...)
+	sort.Strings(sortedFiles)
+
+	output, err := exec.Command("git", append([]string{"hash-object", "--"}, sortedFiles...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := strings.Fields(string(output))
+	if len(hashes) != len(sortedFiles) {
+		return nil, errUnexpectedHashes
+	}
+
+	blobs := make([]string, 0, len(hashes))
+	for i, hash := range hashes {
+		blobs = append(blobs, sortedFiles[i]+" "+hash)
+	}
+
+	return blobs, nil
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newTestRepository creates a git repository in a temporary directory and
+// changes to it until the end of the test.
+func newTestRepository(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	previousDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previousDir) })
+
+	runGit(t, "init", "-q")
+	runGit(t, "config", "user.name", "Test")
+	runGit(t, "config", "user.email", "test@example.com")
+	runGit(t, "config", "commit.gpgsign", "false")
+
+	return dir
+}
+
+func runGit(t *testing.T, args ...string) string {
+	t.Helper()
+
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s: %s", args, err, output)
+	}
+
+	return string(output)
+}
+
+func writeTestFile(t *testing.T, file string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheKeyUsesTheStagedContent(t *testing.T) {
+	dir := newTestRepository(t)
+	cache := &Cache{Dir: filepath.Join(dir, ".git", "capn-hook", "cache")}
+	builtin := &ExpandedCommand{Command: &Command{Builtin: "trailing-whitespace"}, Line: "builtin:trailing-whitespace", Files: []string{"a.txt"}}
+
+	stagedKey := func() string {
+		source, err := NewFileSource(IndexRevision, builtin.Files)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key := cache.Key(dir, builtin, nil, source)
+		if key == "" {
+			t.Fatal("Key() returned an empty key")
+		}
+		return key
+	}
+
+	writeTestFile(t, "a.txt", "clean\n")
+	runGit(t, "add", "a.txt")
+	writeTestFile(t, "a.txt", "dirty \n")
+
+	cleanKey := stagedKey()
+	if workingTreeKey := cache.Key(dir, builtin, nil, nil); workingTreeKey == cleanKey {
+		t.Error("the key of the staged content is the same as the one of the working tree")
+	}
+
+	if key := stagedKey(); key != cleanKey {
+		t.Errorf("the key changed without changing the index: %s != %s", key, cleanKey)
+	}
+
+	runGit(t, "add", "a.txt")
+	if key := stagedKey(); key == cleanKey {
+		t.Error("the key didn't change after staging different content")
+	}
+}
+
+func TestCacheKeyOfCommandsIncludesTheWorkingTree(t *testing.T) {
+	dir := newTestRepository(t)
+	cache := &Cache{Dir: filepath.Join(dir, ".git", "capn-hook", "cache")}
+	command := &ExpandedCommand{Command: &Command{Run: "lint {files}"}, Line: "lint a.txt", Files: []string{"a.txt"}}
+
+	writeTestFile(t, "a.txt", "one\n")
+	runGit(t, "add", "a.txt")
+	source, err := NewFileSource(IndexRevision, command.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := cache.Key(dir, command, nil, source)
+	writeTestFile(t, "a.txt", "two\n")
+	if cache.Key(dir, command, nil, source) == key {
+		t.Error("the key of a command that reads the working tree didn't change with it")
+	}
+}
+
+func TestCacheKeyIncludesTheManifestEnvironment(t *testing.T) {
+	dir := newTestRepository(t)
+	cache := &Cache{Dir: filepath.Join(dir, ".git", "capn-hook", "cache")}
+	command := &ExpandedCommand{Command: &Command{Run: "lint"}, Line: "lint", Files: []string{}}
+
+	one := cache.Key(dir, command, SetEnv(os.Environ(), "CAPN_HOOK_TEST_VALUE", "one"), nil)
+	two := cache.Key(dir, command, SetEnv(os.Environ(), "CAPN_HOOK_TEST_VALUE", "two"), nil)
+	if one == "" || one == two {
+		t.Errorf("the keys with different variables are %q and %q", one, two)
+	}
+
+	if cache.Key(dir, command, os.Environ(), nil) != cache.Key(dir, command, nil, nil) {
+		t.Error("the variables of the process changed the key")
+	}
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"bufio"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+)
+
+// LineRange is a range of lines, both ends included.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// ChangedLines are the added or modified lines of each file.
+type ChangedLines map[string][]LineRange
+
+// FindChangedLines returns the lines changed by `git diff -U0` with the given
+// arguments. The paths are relative to the given directory.
+func FindChangedLines(dir string, diffArgs ...string) (ChangedLines, error) {
+	args := append([]string{"diff", "-U0", "--no-color", "--no-ext-diff", "--relative", "--src-prefix=a/", "--dst-prefix=b/"}, diffArgs...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseChangedLines(string(output)), nil
+}
+
+// ParseChangedLines parses the hunks of a diff generated with -U0. The file
+// headers are only read before the first hunk of every file, so added lines
+// starting with "++ " are not mistaken for them.
+func ParseChangedLines(diff string) ChangedLines {
+	changedLines := ChangedLines{}
+	currentFile := ""
+	inHeader := true
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "diff --git ") {
+			inHeader = true
+			currentFile = ""
+			continue
+		}
+
+		if inHeader && strings.HasPrefix(line, "+++ ") {
+			currentFile = ""
+			if path := diffPath(strings.TrimPrefix(line, "+++ ")); path != "/dev/null" {
+				currentFile = strings.TrimPrefix(path, "b/")
+				changedLines[currentFile] = []LineRange{}
+			}
+			continue
+		}
+
+		match := hunkHeader.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		inHeader = false
+		if currentFile == "" {
+			continue
+		}
+
+		start, _ := strconv.Atoi(match[1])
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+
+		if count > 0 {
+			changedLines[currentFile] = append(changedLines[currentFile], LineRange{Start: start, End: start + count - 1})
+		}
+	}
+
+	return changedLines
+}
+
+// diffPath returns the path of a file header of a diff, unquoting the paths that
+// git writes C-quoted, e.g. the ones with non-ASCII characters.
+func diffPath(path string) string {
+	// git ends the headers of paths with spaces with a tab
+	path = strings.TrimSuffix(path, "\t")
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+
+	return path
+}
+
+// Contains returns true if the given line of the file was changed. A line of 0
+// refers to the whole file and is contained if the file changed at all.
+func (changedLines ChangedLines) Contains(file string, line int) bool {
+	ranges, ok := changedLines[file]
+	if !ok {
+		return false
+	}
+
+	if line <= 0 {
+		return true
+	}
+
+	for _, lineRange := range ranges {
+		if line >= lineRange.Start && line <= lineRange.End {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns the diagnostics that are on changed lines, and the ones that
+// are not in a file.
+func (changedLines ChangedLines) Filter(diagnostics []*Diagnostic) []*Diagnostic {
+	filtered := []*Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.File == "" || changedLines.Contains(diagnostic.File, diagnostic.Line) {
+			filtered = append(filtered, diagnostic)
+		}
+	}
+
+	return filtered
+}
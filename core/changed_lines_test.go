@@ -0,0 +1,83 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChangedLines(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want ChangedLines
+	}{
+		{
+			name: "empty diff",
+			diff: "",
+			want: ChangedLines{},
+		},
+		{
+			name: "hunk with a count",
+			diff: "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -10,2 +12,3 @@ func main() {\n",
+			want: ChangedLines{"main.go": {{Start: 12, End: 14}}},
+		},
+		{
+			name: "hunk without a count is a single line",
+			diff: "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -3 +3 @@\n",
+			want: ChangedLines{"main.go": {{Start: 3, End: 3}}},
+		},
+		{
+			name: "hunk that only removes lines",
+			diff: "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -7,2 +6,0 @@\n",
+			want: ChangedLines{"main.go": {}},
+		},
+		{
+			name: "several hunks and files",
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1,2 @@\n@@ -20,0 +21,4 @@\n" +
+				"diff --git a/dir/b.go b/dir/b.go\n--- a/dir/b.go\n+++ b/dir/b.go\n@@ -5,1 +5,1 @@\n",
+			want: ChangedLines{
+				"a.go":     {{Start: 1, End: 2}, {Start: 21, End: 24}},
+				"dir/b.go": {{Start: 5, End: 5}},
+			},
+		},
+		{
+			name: "new file",
+			diff: "diff --git a/new.go b/new.go\nnew file mode 100644\n--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,5 @@\n",
+			want: ChangedLines{"new.go": {{Start: 1, End: 5}}},
+		},
+		{
+			name: "deleted file",
+			diff: "diff --git a/old.go b/old.go\ndeleted file mode 100644\n--- a/old.go\n+++ /dev/null\n@@ -1,5 +0,0 @@\n",
+			want: ChangedLines{},
+		},
+		{
+			name: "added lines that look like hunk headers",
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1,2 @@\n+@@ -1 +1,100 @@\n",
+			want: ChangedLines{"a.go": {{Start: 1, End: 2}}},
+		},
+		{
+			name: "added and removed lines that look like file headers",
+			diff: "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -2 +2 @@\n--- b/other.txt\n+++ b/other.txt\n@@ -9,0 +10,2 @@\n+++ /dev/null\n+x\n",
+			want: ChangedLines{"a.txt": {{Start: 2, End: 2}, {Start: 10, End: 11}}},
+		},
+		{
+			name: "path with spaces",
+			diff: "diff --git a/a b.go b/a b.go\n--- a/a b.go\t\n+++ b/a b.go\t\n@@ -1 +1 @@\n",
+			want: ChangedLines{"a b.go": {{Start: 1, End: 1}}},
+		},
+		{
+			name: "quoted path",
+			diff: "diff --git \"a/caf\\303\\251.go\" \"b/caf\\303\\251.go\"\n--- \"a/caf\\303\\251.go\"\n+++ \"b/caf\\303\\251.go\"\n@@ -4,0 +5 @@\n",
+			want: ChangedLines{"café.go": {{Start: 5, End: 5}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ParseChangedLines(test.diff)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseChangedLines() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
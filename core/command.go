@@ -0,0 +1,120 @@
+package core
+
+import (
+	"strings"
+)
+
+// Command is a command run by a hook. In the manifest it can be written either
+// as a plain string or as a map with the command to run and its name. Instead
+// of a command line it can run one of the built-in checks with its options.
+type Command struct {
+	Name       string `yaml:"name,omitempty"`
+	ID         string `yaml:"id,omitempty"`
+	Run        string `yaml:"run,omitempty"`
+	Builtin    string `yaml:"builtin,omitempty"`
+	VersionCmd string `yaml:"version_cmd,omitempty"`
+	Format     string `yaml:"format,omitempty"`
+
+	Env     map[string]string      `yaml:"env,omitempty"`
+	Options map[string]interface{} `yaml:"options,omitempty"`
+}
+
+// NewCommands returns the commands for the given command lines.
+func NewCommands(lines ...string) []*Command {
+	commands := make([]*Command, 0, len(lines))
+	for _, line := range lines {
+		commands = append(commands, &Command{Run: line})
+	}
+
+	return commands
+}
+
+// Tool returns the name of the tool run by the command.
+func (command *Command) Tool() string {
+	if identifier := command.Identifier(); identifier != "" {
+		return identifier
+	}
+
+	if command.Builtin != "" {
+		return command.Builtin
+	}
+
+	fields := strings.Fields(command.Run)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
+
+// Line returns the command line, or the name of the built-in check prefixed with builtin:.
+func (command *Command) Line() string {
+	if command.Builtin != "" {
+		return "builtin:" + command.Builtin
+	}
+
+	return command.Run
+}
+
+// ChecksFiles returns false for the built-in checks that don't check files.
+// Only the commands that check files can be cached.
+func (command *Command) ChecksFiles() bool {
+	return !builtinsWithoutFiles[command.Builtin]
+}
+
+// Environment returns the environment of the expanded command: the given
+// environment with the variables of the command and the CAPN_HOOK_* variables.
+func (command *Command) Environment(env []string, options *RunOptions, hook *Hook, expanded *ExpandedCommand) ([]string, error) {
+	env = SetEnv(env, "CAPN_HOOK_NAME", options.HookName)
+	env = SetEnv(env, "CAPN_HOOK_ID", hook.Identifier())
+	env = SetEnv(env, "CAPN_HOOK_COMMAND", command.Identifier())
+	env = SetEnv(env, "CAPN_HOOK_FILES", EscapeStringArray(expanded.Files))
+	env = SetEnv(env, "CAPN_HOOK_ARGS", EscapeStringArray(options.Args))
+	env = SetEnv(env, "CAPN_HOOK_WORKING_DIR", hook.ResolveWorkingDir(options.WorkingDir))
+	if options.Commit != nil {
+		env = options.Commit.environment(env)
+	}
+
+	return applyEnv(env, options.WorkingDir, "", command.Env)
+}
+
+// Identifier returns the id of the command, or its name if it doesn't have one.
+// Built-in checks are identified by the name of the check by default.
+func (command *Command) Identifier() string {
+	if command.ID != "" {
+		return command.ID
+	}
+
+	if command.Name != "" {
+		return command.Name
+	}
+
+	return command.Builtin
+}
+
+// UnmarshalYAML decodes the command from a string or a map.
+func (command *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var line string
+	if err := unmarshal(&line); err == nil {
+		command.Run = line
+		return nil
+	}
+
+	type plainCommand Command
+	return unmarshal((*plainCommand)(command))
+}
+
+// MarshalYAML encodes the command as a string when it only has the command line.
+func (command *Command) MarshalYAML() (interface{}, error) {
+	if command.isPlain() {
+		return command.Run, nil
+	}
+
+	type plainCommand Command
+	return (*plainCommand)(command), nil
+}
+
+func (command *Command) isPlain() bool {
+	return command.Name == "" && command.ID == "" && command.VersionCmd == "" && command.Format == "" && command.Builtin == "" &&
+		len(command.Env) == 0 && len(command.Options) == 0
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+)
+
+var (
+	errUnexpectedCommitFormat = errors.New("git show returned an unexpected commit format")
+)
+
+// Commit is a commit checked by a hook with per_commit enabled.
+type Commit struct {
+	SHA            string
+	Parents        []string
+	AuthorName     string
+	AuthorEmail    string
+	CommitterName  string
+	CommitterEmail string
+	Message        string
+	Files          []string
+	MessageFile    string
+}
+
+// LoadCommit reads the author, committer, message and files of the given commit.
+func LoadCommit(sha string) (*Commit, error) {
+	output, err := exec.Command("git", "show", "-s", "--format=%H%x00%P%x00%an%x00%ae%x00%cn%x00%ce%x00%B", sha).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.SplitN(string(output), "\x00", 7)
+	if len(fields) != 7 {
+		return nil, errUnexpectedCommitFormat
+	}
+
+	commit := &Commit{
+		SHA:            fields[0],
+		Parents:        strings.Fields(fields[1]),
+		AuthorName:     fields[2],
+		AuthorEmail:    fields[3],
+		CommitterName:  fields[4],
+		CommitterEmail: fields[5],
+		Message:        strings.TrimRight(fields[6], "\n") + "\n",
+	}
+
+	// merges are compared with their first parent
+	args := []string{"diff-tree", "--no-commit-id", "--name-only", "-r", "-z", "--root", "--no-renames", "--diff-filter=d"}
+	output, err = exec.Command("git", append(args, commit.diffArgs()...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	commit.Files = splitNull(output)
+
+	return commit, nil
+}
+
+// ShortSHA returns the abbreviated sha of the commit.
+func (commit *Commit) ShortSHA() string {
+	return ShortSHA(commit.SHA)
+}
+
+// Subject returns the first line of the message of the commit.
+func (commit *Commit) Subject() string {
+	return strings.SplitN(commit.Message, "\n", 2)[0]
+}
+
+// WriteMessageFile writes the message of the commit to a temporary file, which
+// the caller removes, and returns its path.
+func (commit *Commit) WriteMessageFile() (string, error) {
+	file, err := ioutil.TempFile("", "capn-hook-commit-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(commit.Message); err != nil {
+		return "", err
+	}
+
+	commit.MessageFile = file.Name()
+	return commit.MessageFile, nil
+}
+
+// diffArgs returns the arguments of git diff that show the changes of the
+// commit against its first parent.
+func (commit *Commit) diffArgs() []string {
+	if len(commit.Parents) == 0 {
+		return []string{commit.SHA}
+	}
+
+	return []string{commit.Parents[0], commit.SHA}
+}
+
+// templateVars returns the template variables with the details of the commit.
+func (commit *Commit) templateVars() Vars {
+	return Vars{
+		"commit":              commit.SHA,
+		"commit_message_file": commit.MessageFile,
+		"author_email":        commit.AuthorEmail,
+	}
+}
+
+// environment returns the given environment with the details of the commit.
+func (commit *Commit) environment(env []string) []string {
+	env = SetEnv(env, "CAPN_HOOK_COMMIT", commit.SHA)
+	env = SetEnv(env, "CAPN_HOOK_COMMIT_MESSAGE_FILE", commit.MessageFile)
+	env = SetEnv(env, "CAPN_HOOK_AUTHOR_NAME", commit.AuthorName)
+	env = SetEnv(env, "CAPN_HOOK_AUTHOR_EMAIL", commit.AuthorEmail)
+	env = SetEnv(env, "CAPN_HOOK_COMMITTER_NAME", commit.CommitterName)
+	env = SetEnv(env, "CAPN_HOOK_COMMITTER_EMAIL", commit.CommitterEmail)
+
+	return env
+}
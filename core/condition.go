@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// StringList is a list of strings that can be written in the manifest as a single string.
+type StringList []string
+
+// UnmarshalYAML decodes the list from a string or a list of strings.
+func (list *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err == nil {
+		*list = StringList{value}
+		return nil
+	}
+
+	var values []string
+	if err := unmarshal(&values); err != nil {
+		return err
+	}
+
+	*list = StringList(values)
+	return nil
+}
+
+// Condition decides whether a hook runs. All the given conditions must be met.
+type Condition struct {
+	Branch StringList `yaml:"branch,omitempty"`
+	Env    StringList `yaml:"env,omitempty"`
+	Exists StringList `yaml:"exists,omitempty"`
+	Which  StringList `yaml:"which,omitempty"`
+	RunIf  string     `yaml:"run_if,omitempty"`
+}
+
+// Evaluate returns true if the conditions are met, or the reason why they aren't.
+// The run_if command is not run when dryRun is true.
+func (condition *Condition) Evaluate(workingDir string, env []string, dryRun bool) (bool, string) {
+	if len(condition.Branch) > 0 {
+		branch := CurrentBranch()
+		if !matchAny(condition.Branch, branch) {
+			return false, fmt.Sprintf("branch %q doesn't match %s", branch, strings.Join(condition.Branch, ", "))
+		}
+	}
+
+	for _, envCondition := range condition.Env {
+		parts := strings.SplitN(envCondition, "=", 2)
+		value, ok := lookupEnvVar(env, parts[0])
+		if !ok {
+			return false, fmt.Sprintf("%s is not set", parts[0])
+		}
+
+		if len(parts) == 2 && value != parts[1] {
+			return false, fmt.Sprintf("%s is not %q", parts[0], parts[1])
+		}
+	}
+
+	for _, file := range condition.Exists {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(workingDir, file)
+		}
+
+		if _, err := os.Stat(file); err != nil {
+			return false, fmt.Sprintf("%s doesn't exist", file)
+		}
+	}
+
+	for _, executable := range condition.Which {
+		if _, ok := findExecutable(executable, env); !ok {
+			return false, fmt.Sprintf("%s is not installed", executable)
+		}
+	}
+
+	if condition.RunIf != "" && !dryRun {
+		cmdParts := strings.Split(condition.RunIf, " ")
+		cmd := exec.Command(lookPath(cmdParts[0], env), cmdParts[1:]...)
+		cmd.Dir = workingDir
+		cmd.Env = env
+
+		if err := cmd.Run(); err != nil {
+			return false, fmt.Sprintf("%s failed", condition.RunIf)
+		}
+	}
+
+	return true, ""
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
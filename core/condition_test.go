@@ -0,0 +1,89 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestConditionEvaluate(t *testing.T) {
+	dir := newTestRepository(t)
+	runGit(t, "checkout", "-q", "-b", "feature/login")
+	writeTestFile(t, "package.json", "{}\n")
+
+	bin := filepath.Join(dir, "bin")
+	writeTestFile(t, filepath.Join(bin, "mytool"), "#!/bin/sh\n")
+	if err := os.Chmod(filepath.Join(bin, "mytool"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"PATH=" + bin + string(os.PathListSeparator) + os.Getenv("PATH"), "CI=true", "EMPTY="}
+
+	tests := []struct {
+		name      string
+		condition *Condition
+		dryRun    bool
+		want      bool
+	}{
+		{"no conditions", &Condition{}, false, true},
+		{"branch", &Condition{Branch: StringList{"feature/*"}}, false, true},
+		{"one of the branches", &Condition{Branch: StringList{"main", "feature/login"}}, false, true},
+		{"other branch", &Condition{Branch: StringList{"main"}}, false, false},
+		{"branch patterns don't cross slashes", &Condition{Branch: StringList{"*"}}, false, false},
+		{"env set", &Condition{Env: StringList{"CI"}}, false, true},
+		{"env set to an empty value", &Condition{Env: StringList{"EMPTY"}}, false, true},
+		{"env not set", &Condition{Env: StringList{"DEPLOY"}}, false, false},
+		{"env with the value", &Condition{Env: StringList{"CI=true"}}, false, true},
+		{"env with another value", &Condition{Env: StringList{"CI=false"}}, false, false},
+		{"existing file", &Condition{Exists: StringList{"package.json"}}, false, true},
+		{"missing file", &Condition{Exists: StringList{"Gemfile"}}, false, false},
+		{"installed tool", &Condition{Which: StringList{"mytool"}}, false, true},
+		{"missing tool", &Condition{Which: StringList{"capn-hook-missing-tool"}}, false, false},
+		{"run_if succeeds", &Condition{RunIf: "true"}, false, true},
+		{"run_if fails", &Condition{RunIf: "false"}, false, false},
+		{"run_if is not run in a dry run", &Condition{RunIf: "false"}, true, true},
+		{"all the conditions must be met", &Condition{Env: StringList{"CI"}, Exists: StringList{"Gemfile"}}, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok, reason := test.condition.Evaluate(dir, env, test.dryRun)
+			if ok != test.want {
+				t.Errorf("Evaluate() = %v (%s), want %v", ok, reason, test.want)
+			}
+
+			if !ok && reason == "" {
+				t.Error("Evaluate() returned no reason")
+			}
+		})
+	}
+}
+
+func TestStringListUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		document string
+		want     StringList
+	}{
+		{"branch: main", StringList{"main"}},
+		{"branch: [main, develop]", StringList{"main", "develop"}},
+		{"branch:\n- main\n- release/*", StringList{"main", "release/*"}},
+	}
+
+	for _, test := range tests {
+		condition := &Condition{}
+		if err := yaml.Unmarshal([]byte(test.document), condition); err != nil {
+			t.Errorf("Unmarshal(%q) error = %v", test.document, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(condition.Branch, test.want) {
+			t.Errorf("Unmarshal(%q) = %v, want %v", test.document, condition.Branch, test.want)
+		}
+	}
+
+	if err := yaml.Unmarshal([]byte("branch: {name: main}"), &Condition{}); err == nil {
+		t.Error("Unmarshal() of a map error = nil, want an error")
+	}
+}
@@ -6,10 +6,10 @@ func DefaultManifest() *Manifest {
 		PreCommit: []*Hook{
 			&Hook{
 				Pattern: "*",
-				Run: []string{
+				Run: NewCommands(
 					"echo {files}",
 					"echo {file}",
-				},
+				),
 				Required: false,
 			},
 		},
@@ -22,30 +22,28 @@ func DefaultGolangManifest() *Manifest {
 		PreCommit: []*Hook{
 			&Hook{
 				Pattern: "*.go",
-				Run: []string{
-					"golint -min_confidence 0.3 -set_exit_status {files}",
-					"gocyclo -over 10 {file}",
-					"varcheck",
-					"deadcode",
-					"structcheck",
+				Run: []*Command{
+					{Builtin: "gofmt"},
+					{Builtin: "go-vet"},
+					{Run: "gocyclo -over 10 {file}"},
 				},
 				Required: true,
 			},
 		},
 		PrePush: []*Hook{
 			&Hook{
-				Run: []string{
+				Run: NewCommands(
 					"go test .",
-				},
+				),
 				Required: true,
 			},
 		},
 		PostReceive: []*Hook{
 			&Hook{
 				Pattern: "glide.*",
-				Run: []string{
+				Run: NewCommands(
 					"glide install",
-				},
+				),
 				Required: false,
 			},
 		},
@@ -58,25 +56,25 @@ func DefaultRubyManifest() *Manifest {
 		PreCommit: []*Hook{
 			&Hook{
 				Pattern: "*.rb",
-				Run: []string{
+				Run: NewCommands(
 					"rubycritic -f console {files}",
-				},
+				),
 				Required: true,
 			},
 			&Hook{
 				Pattern: "Gemfile*",
-				Run: []string{
+				Run: NewCommands(
 					"dawn -z -K .",
-				},
+				),
 				Required: true,
 			},
 		},
 		PostReceive: []*Hook{
 			&Hook{
 				Pattern: "Gemfile*",
-				Run: []string{
+				Run: NewCommands(
 					"bundle install",
-				},
+				),
 				Required: false,
 			},
 		},
@@ -89,16 +87,16 @@ func DefaultAndroidManifest() *Manifest {
 		PreCommit: []*Hook{
 			&Hook{
 				Pattern: "*.java",
-				Run: []string{
+				Run: NewCommands(
 					"lint .",
-				},
+				),
 				Required: false,
 			},
 			&Hook{
 				Pattern: "*.xml",
-				Run: []string{
+				Run: NewCommands(
 					"lint .",
-				},
+				),
 				Required: false,
 			},
 		},
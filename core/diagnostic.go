@@ -0,0 +1,258 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// DiagnosticError is the severity of the diagnostics that are errors.
+	DiagnosticError = "error"
+
+	// DiagnosticWarning is the severity of the diagnostics that are warnings.
+	DiagnosticWarning = "warning"
+
+	// DiagnosticNote is the severity of the informative diagnostics.
+	DiagnosticNote = "note"
+
+	gnuFormat = regexp.MustCompile(`^(?P<file>[^:\s][^:]*):(?P<line>\d+):(?:(?P<col>\d+):)?\s*(?:(?P<severity>error|warning|note|info):\s*)?(?P<message>.+)$`)
+
+	diagnosticParsers = map[string]func(string) ([]*Diagnostic, error){
+		"gnu":          parseGNUDiagnostics,
+		"golint":       parseGNUDiagnostics,
+		"eslint-json":  parseESLintDiagnostics,
+		"rubocop-json": parseRubocopDiagnostics,
+	}
+)
+
+// Diagnostic is a problem reported by a tool on a file.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Rule     string `json:"rule,omitempty"`
+	Tool     string `json:"tool,omitempty"`
+}
+
+// String returns the diagnostic as file:line:col: message, without the
+// location when the problem is not in a file.
+func (diagnostic *Diagnostic) String() string {
+	if diagnostic.File == "" {
+		return fmt.Sprintf("%s: %s", diagnostic.Severity, diagnostic.Message)
+	}
+
+	location := diagnostic.File
+	if diagnostic.Line > 0 {
+		location += fmt.Sprintf(":%d", diagnostic.Line)
+	}
+	if diagnostic.Column > 0 {
+		location += fmt.Sprintf(":%d", diagnostic.Column)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", location, diagnostic.Severity, diagnostic.Message)
+}
+
+// ParseDiagnostics parses the output of a tool using the given format. The format
+// can be either gnu, golint, eslint-json, rubocop-json or a regular expression
+// with the named groups file, line, col, severity, rule and message.
+func ParseDiagnostics(format string, output string) ([]*Diagnostic, error) {
+	if parser, ok := diagnosticParsers[format]; ok {
+		return parser(output)
+	}
+
+	expr, err := regexp.Compile(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRegexpDiagnostics(expr, output), nil
+}
+
+// GroupDiagnosticsByFile returns the diagnostics of each file, sorted by line.
+func GroupDiagnosticsByFile(diagnostics []*Diagnostic) ([]string, map[string][]*Diagnostic) {
+	files := []string{}
+	groups := map[string][]*Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		if _, ok := groups[diagnostic.File]; !ok {
+			files = append(files, diagnostic.File)
+		}
+		groups[diagnostic.File] = append(groups[diagnostic.File], diagnostic)
+	}
+
+	sort.Strings(files)
+	for _, file := range files {
+		group := groups[file]
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].Line != group[j].Line {
+				return group[i].Line < group[j].Line
+			}
+			return group[i].Column < group[j].Column
+		})
+	}
+
+	return files, groups
+}
+
+// PrintDiagnostics prints a summary of the diagnostics grouped by file.
+func PrintDiagnostics(diagnostics []*Diagnostic) {
+	if len(diagnostics) == 0 {
+		return
+	}
+
+	Out.Printf("# %d problem(s) found\n", len(diagnostics))
+	files, groups := GroupDiagnosticsByFile(diagnostics)
+	for _, file := range files {
+		Out.Println(Out.Colorize(colorBold, valueOrDefault(file, "(repository)")))
+		for _, diagnostic := range groups[file] {
+			position := fmt.Sprintf("%d:%d", diagnostic.Line, diagnostic.Column)
+			Out.Printf("  %-8s %s %s (%s)\n", position, Out.severity(diagnostic.Severity), diagnostic.Message, diagnostic.Tool)
+		}
+	}
+}
+
+func parseGNUDiagnostics(output string) ([]*Diagnostic, error) {
+	return parseRegexpDiagnostics(gnuFormat, output), nil
+}
+
+func parseRegexpDiagnostics(expr *regexp.Regexp, output string) []*Diagnostic {
+	diagnostics := []*Diagnostic{}
+	for _, line := range strings.Split(output, "\n") {
+		match := expr.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+
+		diagnostic := &Diagnostic{Severity: DiagnosticError}
+		for i, name := range expr.SubexpNames() {
+			value := strings.TrimSpace(match[i])
+			if value == "" {
+				continue
+			}
+
+			switch name {
+			case "file":
+				diagnostic.File = filepath.ToSlash(value)
+			case "line":
+				diagnostic.Line, _ = strconv.Atoi(value)
+			case "col":
+				diagnostic.Column, _ = strconv.Atoi(value)
+			case "severity":
+				diagnostic.Severity = normalizeSeverity(value)
+			case "rule":
+				diagnostic.Rule = value
+			case "message":
+				diagnostic.Message = value
+			}
+		}
+
+		if diagnostic.File != "" && diagnostic.Message != "" {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	return diagnostics
+}
+
+type eslintFile struct {
+	FilePath string `json:"filePath"`
+	Messages []struct {
+		RuleID   string `json:"ruleId"`
+		Severity int    `json:"severity"`
+		Message  string `json:"message"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+	} `json:"messages"`
+}
+
+func parseESLintDiagnostics(output string) ([]*Diagnostic, error) {
+	files := []*eslintFile{}
+	if err := json.Unmarshal([]byte(jsonDocument(output, "[")), &files); err != nil {
+		return nil, err
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, file := range files {
+		for _, message := range file.Messages {
+			severity := DiagnosticWarning
+			if message.Severity >= 2 {
+				severity = DiagnosticError
+			}
+
+			diagnostics = append(diagnostics, &Diagnostic{
+				File:     filepath.ToSlash(file.FilePath),
+				Line:     message.Line,
+				Column:   message.Column,
+				Severity: severity,
+				Message:  message.Message,
+				Rule:     message.RuleID,
+			})
+		}
+	}
+
+	return diagnostics, nil
+}
+
+type rubocopReport struct {
+	Files []struct {
+		Path     string `json:"path"`
+		Offenses []struct {
+			Severity string `json:"severity"`
+			Message  string `json:"message"`
+			CopName  string `json:"cop_name"`
+			Location struct {
+				Line   int `json:"line"`
+				Column int `json:"column"`
+			} `json:"location"`
+		} `json:"offenses"`
+	} `json:"files"`
+}
+
+func parseRubocopDiagnostics(output string) ([]*Diagnostic, error) {
+	report := &rubocopReport{}
+	if err := json.Unmarshal([]byte(jsonDocument(output, "{")), report); err != nil {
+		return nil, err
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, file := range report.Files {
+		for _, offense := range file.Offenses {
+			diagnostics = append(diagnostics, &Diagnostic{
+				File:     filepath.ToSlash(file.Path),
+				Line:     offense.Location.Line,
+				Column:   offense.Location.Column,
+				Severity: normalizeSeverity(offense.Severity),
+				Message:  offense.Message,
+				Rule:     offense.CopName,
+			})
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// jsonDocument skips anything the tool printed before the JSON document.
+func jsonDocument(output string, start string) string {
+	if index := strings.Index(output, start); index > 0 {
+		return output[index:]
+	}
+
+	return output
+}
+
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "warning", "warn", "convention", "refactor":
+		return DiagnosticWarning
+	case "note", "info", "information":
+		return DiagnosticNote
+	}
+
+	return DiagnosticError
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		output string
+		want   []*Diagnostic
+	}{
+		{
+			name:   "gnu",
+			format: "gnu",
+			output: "main.go:3:7: error: undefined: x\nlib/a.c:10: warning: unused variable\nlib/a.c:4:2: info: declared here\nmain.go:8:1: missing return\n",
+			want: []*Diagnostic{
+				{File: "main.go", Line: 3, Column: 7, Severity: DiagnosticError, Message: "undefined: x"},
+				{File: "lib/a.c", Line: 10, Severity: DiagnosticWarning, Message: "unused variable"},
+				{File: "lib/a.c", Line: 4, Column: 2, Severity: DiagnosticNote, Message: "declared here"},
+				{File: "main.go", Line: 8, Column: 1, Severity: DiagnosticError, Message: "missing return"},
+			},
+		},
+		{
+			name:   "gnu ignores the lines that are not problems",
+			format: "gnu",
+			output: "make: *** [all] Error 1\n  main.go:1:1: indented\n\nFAIL\n",
+			want:   []*Diagnostic{},
+		},
+		{
+			name:   "golint",
+			format: "golint",
+			output: "cmd/run.go:42:1: exported function Run should have comment or be unexported\r\n",
+			want:   []*Diagnostic{{File: "cmd/run.go", Line: 42, Column: 1, Severity: DiagnosticError, Message: "exported function Run should have comment or be unexported"}},
+		},
+		{
+			name:   "eslint-json",
+			format: "eslint-json",
+			output: "> eslint --format json .\n" + `[{"filePath":"src/a.js","messages":[{"ruleId":"no-unused-vars","severity":2,"message":"'x' is unused","line":1,"column":5},{"ruleId":null,"severity":1,"message":"Parsing warning","line":3,"column":1}]},{"filePath":"src/b.js","messages":[]}]`,
+			want: []*Diagnostic{
+				{File: "src/a.js", Line: 1, Column: 5, Severity: DiagnosticError, Rule: "no-unused-vars", Message: "'x' is unused"},
+				{File: "src/a.js", Line: 3, Column: 1, Severity: DiagnosticWarning, Message: "Parsing warning"},
+			},
+		},
+		{
+			name:   "rubocop-json",
+			format: "rubocop-json",
+			output: `{"metadata":{},"files":[{"path":"app/a.rb","offenses":[{"severity":"convention","message":"Prefer single quotes","cop_name":"Style/StringLiterals","location":{"line":2,"column":3}},{"severity":"fatal","message":"unexpected end","cop_name":"Lint/Syntax","location":{"line":9,"column":1}}]}]}`,
+			want: []*Diagnostic{
+				{File: "app/a.rb", Line: 2, Column: 3, Severity: DiagnosticWarning, Rule: "Style/StringLiterals", Message: "Prefer single quotes"},
+				{File: "app/a.rb", Line: 9, Column: 1, Severity: DiagnosticError, Rule: "Lint/Syntax", Message: "unexpected end"},
+			},
+		},
+		{
+			name:   "regexp with file, line, column and message",
+			format: `^(?P<file>[^:]+):(?P<line>\d+):(?P<col>\d+): (?P<message>.+)$`,
+			output: "main.go:3:7: unused variable\nok\n",
+			want:   []*Diagnostic{{File: "main.go", Line: 3, Column: 7, Severity: DiagnosticError, Message: "unused variable"}},
+		},
+		{
+			name:   "regexp with severity and rule",
+			format: `^(?P<severity>\w+) (?P<file>\S+) (?P<line>\d+) \[(?P<rule>[^\]]+)\] (?P<message>.+)$`,
+			output: "warn a.rb 1 [Style/Quotes] prefer single quotes\r\ninfo b.rb 2 [Lint/Todo] todo found\nerror c.rb 3 [Lint/Syntax] unexpected end\n",
+			want: []*Diagnostic{
+				{File: "a.rb", Line: 1, Severity: DiagnosticWarning, Rule: "Style/Quotes", Message: "prefer single quotes"},
+				{File: "b.rb", Line: 2, Severity: DiagnosticNote, Rule: "Lint/Todo", Message: "todo found"},
+				{File: "c.rb", Line: 3, Severity: DiagnosticError, Rule: "Lint/Syntax", Message: "unexpected end"},
+			},
+		},
+		{
+			name:   "regexp ignores the lines without a file or a message",
+			format: `^(?P<file>[^:]*):(?P<message>.*)$`,
+			output: ":no file\nno-message.go:\nfile.go:message\n",
+			want:   []*Diagnostic{{File: "file.go", Severity: DiagnosticError, Message: "message"}},
+		},
+		{
+			name:   "regexp without matches",
+			format: `^(?P<file>\S+):(?P<line>\d+): (?P<message>.+)$`,
+			output: "all good\n",
+			want:   []*Diagnostic{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseDiagnostics(test.format, test.output)
+			if err != nil {
+				t.Fatalf("ParseDiagnostics() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseDiagnostics() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseDiagnosticsWithInvalidOutput(t *testing.T) {
+	tests := []struct {
+		format string
+		output string
+	}{
+		{`(?P<file>[`, "a.go:1: message"},
+		{"eslint-json", "Oops! Something went wrong"},
+		{"rubocop-json", "{\"files\": ["},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseDiagnostics(test.format, test.output); err == nil {
+			t.Errorf("ParseDiagnostics(%q, %q) error = nil, want an error", test.format, test.output)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DryRun prints what RunCommands would do with the given options without running anything.
+func (hook *Hook) DryRun(options *RunOptions) {
+	Out.Printf("Pattern: %s\n", valueOrNone(hook.Pattern))
+	if len(hook.Types) > 0 {
+		Out.Printf("Types: %s\n", strings.Join(hook.Types, ", "))
+	}
+
+	if !options.RunsHook(hook) {
+		Out.Println("Skipped: not selected by --only/--skip/SKIP")
+		return
+	}
+
+	if hook.If != nil {
+		if ok, reason := hook.If.Evaluate(options.WorkingDir, options.environment(), true); !ok {
+			Out.Printf("Skipped: %s\n", reason)
+			return
+		}
+
+		if hook.If.RunIf != "" {
+			Out.Printf("Condition: %s (not evaluated in a dry run)\n", hook.If.RunIf)
+		}
+	}
+
+	if hook.PerCommit {
+		hook.dryRunPerCommit(options)
+		return
+	}
+
+	hook.dryRunCommands(options)
+}
+
+// dryRunCommands prints the files the hook would check and its commands.
+func (hook *Hook) dryRunCommands(options *RunOptions) {
+	filteredFiles, rejectedFiles := hook.Explain(options.Files, options.Source)
+	Out.Println("Matched files:")
+	printList(filteredFiles)
+
+	Out.Println("Not matched files:")
+	rejections := make([]string, 0, len(rejectedFiles))
+	for _, rejected := range rejectedFiles {
+		rejections = append(rejections, fmt.Sprintf("%s (%s)", rejected.File, rejected.Reason))
+	}
+	printList(rejections)
+
+	if len(filteredFiles) == 0 && hook.HasFileFilter() {
+		Out.Println("Skipped: no matching files")
+		return
+	}
+
+	workingDir := hook.ResolveWorkingDir(options.WorkingDir)
+	hookEnv, envErr := hook.Environment(options.environment(), options.WorkingDir)
+	Out.Printf("Commands (in %s):\n", workingDir)
+	for _, command := range hook.Run {
+		if !options.RunsCommand(hook, command) {
+			Out.Printf("  %s (skipped: not selected by --only/--skip/SKIP)\n", command.Line())
+			continue
+		}
+
+		expandedCommands := options.expandCommand(hook, command, filteredFiles)
+		if len(expandedCommands) == 0 {
+			Out.Printf("  %s (skipped: no files to expand it)\n", command.Line())
+		}
+
+		for _, expanded := range expandedCommands {
+			if HasAnyTemplateVariables(expanded.Line) {
+				Out.Printf("  %s (skipped: unresolved template variables)\n", expanded.Line)
+				continue
+			}
+
+			env, err := hookEnv, envErr
+			if err == nil {
+				env, err = command.Environment(hookEnv, options, hook, expanded)
+			}
+
+			if options.usesCache(hook, command) && command.VersionCmd != "" {
+				Out.Printf("  %s (cache not checked: version_cmd is not run in a dry run)\n", expanded.Line)
+				continue
+			}
+
+			if err == nil && options.usesCache(hook, command) {
+				if key := options.Cache.Key(workingDir, expanded, env, options.Source); key != "" && options.Cache.Has(key) {
+					Out.Printf("  %s (skipped: cached)\n", expanded.Line)
+					continue
+				}
+			}
+
+			Out.Printf("  %s\n", expanded.Line)
+		}
+	}
+}
+
+// dryRunPerCommit prints what the hook would do for every commit being pushed.
+func (hook *Hook) dryRunPerCommit(options *RunOptions) {
+	shas, err := options.commits()
+	if err != nil {
+		Out.Printf("Skipped: %s\n", err)
+		return
+	}
+
+	if len(shas) == 0 {
+		Out.Println("Skipped: no commits to check")
+		return
+	}
+
+	for _, sha := range shas {
+		commitOptions, err := options.forCommit(sha)
+		if err != nil {
+			Out.Printf("Commit %s skipped: %s\n", ShortSHA(sha), err)
+			continue
+		}
+
+		commit := commitOptions.Commit
+		Out.Printf("Commit: %s %s\n", commit.ShortSHA(), commit.Subject())
+		hook.dryRunCommands(commitOptions)
+		os.Remove(commit.MessageFile)
+	}
+}
+
+func printList(items []string) {
+	if len(items) == 0 {
+		Out.Println("  (none)")
+		return
+	}
+
+	for _, item := range items {
+		Out.Printf("  %s\n", item)
+	}
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+
+	return value
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// EnvVar is a variable loaded from a dotenv file.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// LoadEnvFile loads the variables of a dotenv file. Values without quotes or in
+// double quotes are expanded with the given environment.
+func LoadEnvFile(path string, env []string) ([]*EnvVar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	vars := []*EnvVar{}
+	lineNumber := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", path, lineNumber)
+		}
+
+		name := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+			value = ExpandEnv(value, env)
+		default:
+			if index := strings.Index(value, " #"); index != -1 {
+				value = strings.TrimSpace(value[:index])
+			}
+			value = ExpandEnv(value, env)
+		}
+
+		vars = append(vars, &EnvVar{Name: name, Value: value})
+		env = SetEnv(env, name, value)
+	}
+
+	return vars, scanner.Err()
+}
+
+// ExpandEnv replaces ${VAR} and $VAR in the value with the variables of the environment.
+func ExpandEnv(value string, env []string) string {
+	return os.Expand(value, func(name string) string {
+		return LookupEnv(env, name)
+	})
+}
+
+// LookupEnv returns the value of the variable in the environment.
+func LookupEnv(env []string, name string) string {
+	value, _ := lookupEnvVar(env, name)
+	return value
+}
+
+func lookupEnvVar(env []string, name string) (string, bool) {
+	prefix := name + "="
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], prefix) {
+			return env[i][len(prefix):], true
+		}
+	}
+
+	return "", false
+}
+
+// SetEnv returns the environment with the variable set to the given value.
+func SetEnv(env []string, name string, value string) []string {
+	prefix := name + "="
+	result := make([]string, 0, len(env)+1)
+	for _, entry := range env {
+		if !strings.HasPrefix(entry, prefix) {
+			result = append(result, entry)
+		}
+	}
+
+	return append(result, prefix+value)
+}
+
+// addedEnv returns the variables of the environment that are not in the one of
+// the process, i.e. the ones set by the manifest, the hook or the command,
+// sorted.
+func addedEnv(env []string) []string {
+	processEnv := map[string]bool{}
+	for _, entry := range os.Environ() {
+		processEnv[entry] = true
+	}
+
+	added := []string{}
+	for _, entry := range env {
+		if !processEnv[entry] {
+			added = append(added, entry)
+		}
+	}
+	sort.Strings(added)
+
+	return added
+}
+
+// applyEnv loads the env file, relative to baseDir, and then sets the variables
+// of the map, sorted by name, expanding them with the environment.
+func applyEnv(env []string, baseDir string, envFile string, vars map[string]string) ([]string, error) {
+	if envFile != "" {
+		path := ExpandEnv(envFile, env)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		fileVars, err := LoadEnvFile(path, env)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fileVar := range fileVars {
+			env = SetEnv(env, fileVar.Name, fileVar.Value)
+		}
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		env = SetEnv(env, name, ExpandEnv(vars[name], env))
+	}
+
+	return env, nil
+}
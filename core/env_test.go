@@ -0,0 +1,134 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	content := `# comment
+PLAIN=value
+export EXPORTED=yes
+  SPACED  =  trimmed
+EXPANDED=$HOME/bin:${PLAIN}
+SINGLE='$HOME stays # here'
+DOUBLE="line\nnext \"quoted\" ${PLAIN}"
+COMMENTED=value # a comment
+HASH=a#b
+EMPTY=
+
+REUSED=$EXPORTED-again
+`
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := LoadEnvFile(path, []string{"HOME=/home/me", "PLAIN=outer"})
+	if err != nil {
+		t.Fatalf("LoadEnvFile() error = %v", err)
+	}
+
+	want := []*EnvVar{
+		{Name: "PLAIN", Value: "value"},
+		{Name: "EXPORTED", Value: "yes"},
+		{Name: "SPACED", Value: "trimmed"},
+		{Name: "EXPANDED", Value: "/home/me/bin:value"},
+		{Name: "SINGLE", Value: "$HOME stays # here"},
+		{Name: "DOUBLE", Value: "line\nnext \"quoted\" value"},
+		{Name: "COMMENTED", Value: "value"},
+		{Name: "HASH", Value: "a#b"},
+		{Name: "EMPTY", Value: ""},
+		{Name: "REUSED", Value: "yes-again"},
+	}
+	if !reflect.DeepEqual(vars, want) {
+		for _, v := range vars {
+			t.Logf("%s=%q", v.Name, v.Value)
+		}
+		t.Errorf("LoadEnvFile() returned unexpected variables")
+	}
+}
+
+func TestLoadEnvFileWithInvalidLines(t *testing.T) {
+	for _, content := range []string{"NO_EQUALS\n", "=value\n", "OK=1\n  = x\n"} {
+		path := filepath.Join(t.TempDir(), ".env")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadEnvFile(path, nil); err == nil {
+			t.Errorf("LoadEnvFile(%q) error = nil, want an error", content)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	env := []string{"NAME=old", "DIR=/tmp", "NAME=new"}
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"$NAME", "new"},
+		{"${NAME}", "new"},
+		{"${DIR}/$NAME.txt", "/tmp/new.txt"},
+		{"$MISSING-x", "-x"},
+		{"${MISSING}", ""},
+	}
+
+	for _, test := range tests {
+		if got := ExpandEnv(test.value, env); got != test.want {
+			t.Errorf("ExpandEnv(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestSetEnv(t *testing.T) {
+	env := SetEnv([]string{"A=1", "B=2", "A=3"}, "A", "4")
+	if want := []string{"B=2", "A=4"}; !reflect.DeepEqual(env, want) {
+		t.Errorf("SetEnv() = %v, want %v", env, want)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.env"), []byte("FROM_FILE=file\nOVERRIDDEN=file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := applyEnv([]string{"ENV_NAME=test"}, dir, "${ENV_NAME}.env", map[string]string{
+		"OVERRIDDEN": "map",
+		"B_USES_A":   "${A_FIRST}-b",
+		"A_FIRST":    "a",
+	})
+	if err != nil {
+		t.Fatalf("applyEnv() error = %v", err)
+	}
+
+	want := map[string]string{"FROM_FILE": "file", "OVERRIDDEN": "map", "A_FIRST": "a", "B_USES_A": "a-b"}
+	for name, value := range want {
+		if got := LookupEnv(env, name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	if _, err := applyEnv(nil, dir, "missing.env", nil); err == nil {
+		t.Error("applyEnv() error = nil, want an error for a missing env file")
+	}
+}
+
+func TestAddedEnv(t *testing.T) {
+	env := SetEnv(os.Environ(), "CAPN_HOOK_TEST_B", "2")
+	env = SetEnv(env, "CAPN_HOOK_TEST_A", "1")
+
+	if got, want := addedEnv(env), []string{"CAPN_HOOK_TEST_A=1", "CAPN_HOOK_TEST_B=2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("addedEnv() = %v, want %v", got, want)
+	}
+
+	if got := addedEnv(os.Environ()); len(got) != 0 {
+		t.Errorf("addedEnv() of the process = %v, want nothing", got)
+	}
+}
@@ -0,0 +1,268 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// IndexRevision is the revision of the FileSource that reads the staged files.
+	IndexRevision = ":"
+)
+
+// FileSource reads the files to check from the index or from a commit instead
+// of the working tree, so the checks see what is being committed or pushed even
+// if the working tree differs. A nil FileSource reads the working tree.
+type FileSource struct {
+	Revision string
+
+	entries map[string]*sourceEntry
+}
+
+// sourceEntry is a file of the index or of the tree of a commit.
+type sourceEntry struct {
+	mode   string
+	object string
+	size   int64
+}
+
+// AllFiles returns all the files of the index or of the tree of the commit,
+// not only the ones to check.
+func (source *FileSource) AllFiles() ([]string, error) {
+	if source.Revision == IndexRevision {
+		return FindTrackedFiles()
+	}
+
+	return FindFilesIn(source.Revision)
+}
+
+// NewFileSource finds the given files in the index, when the revision is
+// IndexRevision, or in the tree of the commit. Files that are not there, and
+// submodules, don't exist in the source.
+func NewFileSource(revision string, files []string) (*FileSource, error) {
+	source := &FileSource{Revision: revision, entries: map[string]*sourceEntry{}}
+	if len(files) == 0 {
+		return source, nil
+	}
+
+	args := []string{"ls-tree", "-r", "-z", "--full-tree", revision}
+	if revision == IndexRevision {
+		args = []string{"ls-files", "--stage", "-z"}
+	}
+
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("can't list the files of %s: %s", source.description(), gitError(err))
+	}
+
+	wanted := map[string]bool{}
+	for _, file := range files {
+		wanted[file] = true
+	}
+
+	objects := &bytes.Buffer{}
+	for _, entry := range splitNull(output) {
+		// index: <mode> SP <object> SP <stage> TAB <file>
+		// tree:  <mode> SP <type> SP <object> TAB <file>
+		parts := strings.SplitN(entry, "\t", 2)
+		fields := strings.Fields(parts[0])
+		if len(parts) != 2 || len(fields) != 3 || !wanted[parts[1]] || fields[0] == "160000" {
+			continue
+		}
+
+		object := fields[2]
+		if revision == IndexRevision {
+			object = fields[1]
+		}
+
+		if _, ok := source.entries[parts[1]]; !ok {
+			source.entries[parts[1]] = &sourceEntry{mode: fields[0], object: object}
+			fmt.Fprintln(objects, object)
+		}
+	}
+
+	if err := source.loadSizes(objects); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// loadSizes reads the size of the objects of the entries with git cat-file.
+func (source *FileSource) loadSizes(objects *bytes.Buffer) error {
+	if objects.Len() == 0 {
+		return nil
+	}
+
+	cmd := exec.Command("git", "cat-file", "--batch-check")
+	cmd.Stdin = objects
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("can't read the sizes of the files of %s: %s", source.description(), gitError(err))
+	}
+
+	sizes := map[string]int64{}
+	for _, line := range strings.Split(string(output), "\n") {
+		// <object> SP <type> SP <size>
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if size, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			sizes[fields[0]] = size
+		}
+	}
+
+	for _, entry := range source.entries {
+		entry.size = sizes[entry.object]
+	}
+
+	return nil
+}
+
+// Exists returns true if the file is in the source.
+func (source *FileSource) Exists(file string) bool {
+	if source == nil {
+		_, err := os.Lstat(file)
+		return err == nil
+	}
+
+	_, ok := source.entries[file]
+	return ok
+}
+
+// IsRegular returns true if the file is in the source and is not a symlink.
+func (source *FileSource) IsRegular(file string) bool {
+	if source == nil {
+		info, err := os.Lstat(file)
+		return err == nil && info.Mode().IsRegular()
+	}
+
+	entry, ok := source.entries[file]
+	return ok && (entry.mode == "100644" || entry.mode == "100755")
+}
+
+// Size returns the size of the file in the source.
+func (source *FileSource) Size(file string) (int64, error) {
+	if source == nil {
+		info, err := os.Stat(file)
+		if err != nil {
+			return 0, err
+		}
+
+		return info.Size(), nil
+	}
+
+	entry, ok := source.entries[file]
+	if !ok {
+		return 0, source.notFound(file)
+	}
+
+	return entry.size, nil
+}
+
+// ReadFile returns the content of the file in the source.
+func (source *FileSource) ReadFile(file string) ([]byte, error) {
+	if source == nil {
+		return ioutil.ReadFile(file)
+	}
+
+	entry, ok := source.entries[file]
+	if !ok {
+		return nil, source.notFound(file)
+	}
+
+	content, err := exec.Command("git", "cat-file", "blob", entry.object).Output()
+	if err != nil {
+		return nil, fmt.Errorf("can't read %s from %s: %s", file, source.description(), gitError(err))
+	}
+
+	return content, nil
+}
+
+// FileTypes classifies the file of the source like FileTypes does with the
+// files of the working tree.
+func (source *FileSource) FileTypes(file string) ([]string, error) {
+	if source == nil {
+		return FileTypes(file)
+	}
+
+	entry, ok := source.entries[file]
+	if !ok {
+		return nil, source.notFound(file)
+	}
+
+	if entry.mode == "120000" {
+		return []string{FileTypeSymlink}, nil
+	}
+
+	content, err := source.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > sniffSize {
+		content = content[:sniffSize]
+	}
+
+	return classifyFile(file, entry.mode == "100755", content), nil
+}
+
+// objectIDs returns the object id of each file in the source, sorted by file
+// name, or nothing for the working tree.
+func (source *FileSource) objectIDs(files []string) ([]string, error) {
+	if source == nil {
+		return []string{}, nil
+	}
+
+	sortedFiles := append([]string{}, files...)
+	sort.Strings(sortedFiles)
+
+	objects := make([]string, 0, len(sortedFiles))
+	for _, file := range sortedFiles {
+		entry, ok := source.entries[file]
+		if !ok {
+			return nil, source.notFound(file)
+		}
+		objects = append(objects, file+" "+entry.object)
+	}
+
+	return objects, nil
+}
+
+// missingReason explains why a file that is not in the source is not checked.
+func (source *FileSource) missingReason() string {
+	if source == nil {
+		return "file doesn't exist"
+	}
+
+	return "file is not in " + source.description()
+}
+
+func (source *FileSource) notFound(file string) error {
+	return fmt.Errorf("%s is not in %s", file, source.description())
+}
+
+func (source *FileSource) description() string {
+	if source.Revision == IndexRevision {
+		return "the index"
+	}
+
+	return "the commit " + ShortSHA(source.Revision)
+}
+
+// gitError returns the message that git wrote to stderr for the error, if any.
+func gitError(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+		return strings.TrimSpace(string(exitErr.Stderr))
+	}
+
+	return err.Error()
+}
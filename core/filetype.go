@@ -0,0 +1,201 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	sniffSize = 8000
+)
+
+var (
+	// FileTypeExecutable is the type of the files with the executable bit set.
+	FileTypeExecutable = "executable"
+
+	// FileTypeSymlink is the type of the symbolic links.
+	FileTypeSymlink = "symlink"
+
+	// FileTypeDirectory is the type of the directories.
+	FileTypeDirectory = "directory"
+
+	// FileTypeText is the type of the files that contain text.
+	FileTypeText = "text"
+
+	// FileTypeBinary is the type of the files that contain binary data.
+	FileTypeBinary = "binary"
+
+	extensionTypes = map[string]string{
+		".bash":       "shell",
+		".c":          "c",
+		".css":        "css",
+		".gemspec":    "ruby",
+		".go":         "go",
+		".gradle":     "gradle",
+		".h":          "c",
+		".html":       "html",
+		".java":       "java",
+		".js":         "javascript",
+		".json":       "json",
+		".md":         "markdown",
+		".pl":         "perl",
+		".properties": "properties",
+		".py":         "python",
+		".rake":       "ruby",
+		".rb":         "ruby",
+		".sh":         "shell",
+		".toml":       "toml",
+		".xml":        "xml",
+		".yaml":       "yaml",
+		".yml":        "yaml",
+		".zsh":        "shell",
+	}
+
+	fileNameTypes = map[string]string{
+		"Gemfile":    "ruby",
+		"Rakefile":   "ruby",
+		"Dockerfile": "dockerfile",
+		"Makefile":   "makefile",
+	}
+
+	interpreterTypes = map[string]string{
+		"ash":     "shell",
+		"bash":    "shell",
+		"dash":    "shell",
+		"ksh":     "shell",
+		"sh":      "shell",
+		"zsh":     "shell",
+		"node":    "javascript",
+		"nodejs":  "javascript",
+		"perl":    "perl",
+		"python":  "python",
+		"python2": "python",
+		"python3": "python",
+		"ruby":    "ruby",
+	}
+)
+
+// FileTypes classifies the given file by its extension, its shebang line and its content.
+func FileTypes(path string) ([]string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		return []string{FileTypeSymlink}, nil
+	}
+
+	if info.IsDir() {
+		return []string{FileTypeDirectory}, nil
+	}
+
+	head, err := readFileHead(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return classifyFile(path, info.Mode()&0111 != 0, head), nil
+}
+
+// classifyFile returns the types of a regular file given the first bytes of its content.
+func classifyFile(path string, executable bool, head []byte) []string {
+	types := []string{}
+	if executable {
+		types = append(types, FileTypeExecutable)
+	}
+
+	if fileType, ok := extensionTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		types = append(types, fileType)
+	}
+
+	if fileType, ok := fileNameTypes[filepath.Base(path)]; ok {
+		types = append(types, fileType)
+	}
+
+	if isBinary(head) {
+		return append(types, FileTypeBinary)
+	}
+
+	types = append(types, FileTypeText)
+	if fileType := shebangType(head); fileType != "" && !containsString(types, fileType) {
+		types = append(types, fileType)
+	}
+
+	return types
+}
+
+func readFileHead(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := file.Read(head)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return head[:n], nil
+}
+
+func isBinary(data []byte) bool {
+	if bytes.IndexByte(data, 0) != -1 {
+		return true
+	}
+
+	if len(data) == sniffSize {
+		// the sniffed data may end in the middle of a multi-byte rune.
+		for i := 1; i < utf8.UTFMax && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+
+	return !utf8.Valid(data)
+}
+
+func shebangType(data []byte) string {
+	if !bytes.HasPrefix(data, []byte("#!")) {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(scanner.Text(), "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = field
+				break
+			}
+		}
+	}
+
+	return interpreterTypes[interpreter]
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
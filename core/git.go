@@ -3,6 +3,7 @@ package core
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -18,6 +19,7 @@ const (
 var (
 	errGitDirNotFound = errors.New("GITDIR not found")
 	gitDirFiles       = []string{"HEAD", "config", "description", "index", "objects", "hooks"}
+	bareGitDirFiles   = []string{"HEAD", "config", "objects", "refs", "hooks"}
 )
 
 // GitCommand is a command to be executed by git
@@ -68,6 +70,22 @@ func (gitCommand *GitCommand) RunAndGetOutput() []byte {
 	return data
 }
 
+// Output runs the command and returns its output, or an error with the message
+// of git if it fails.
+func (gitCommand *GitCommand) Output() ([]byte, error) {
+	cmd := exec.Command("git", gitCommand.Args...)
+	if gitCommand.ProcInput != nil {
+		cmd.Stdin = gitCommand.ProcInput
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %s", gitCommand.Args[0], gitError(err))
+	}
+
+	return output, nil
+}
+
 // FindGitDir finds the path to the GITDIR
 func FindGitDir() (string, error) {
 	wd, err := os.Getwd()
@@ -84,18 +102,12 @@ func findGitDirIn(path string, depth int) (string, error) {
 	}
 
 	candidate := filepath.Join(path, ".git")
-	isGitDir := true
-
-	for _, gitDirFile := range gitDirFiles {
-		file := filepath.Join(candidate, gitDirFile)
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			isGitDir = false
-			break
-		}
+	if hasFiles(candidate, gitDirFiles) {
+		return candidate, nil
 	}
 
-	if isGitDir {
-		return candidate, nil
+	if hasFiles(path, bareGitDirFiles) {
+		return path, nil
 	}
 
 	upDir, err := filepath.Abs(filepath.Join(path, ".."))
@@ -106,6 +118,17 @@ func findGitDirIn(path string, depth int) (string, error) {
 	return findGitDirIn(upDir, depth+1)
 }
 
+// hasFiles returns true if all the files exist in the directory.
+func hasFiles(dir string, files []string) bool {
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(dir, file)); os.IsNotExist(err) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // FindModifiedFiles returns the list of all modified files
 func FindModifiedFiles() []string {
 	result := GitDiff("--name-only", "-z")
@@ -114,6 +137,57 @@ func FindModifiedFiles() []string {
 	return result
 }
 
+// FindTrackedFiles returns the list of all files tracked by git
+func FindTrackedFiles() ([]string, error) {
+	command := &GitCommand{Args: []string{"ls-files", "-z"}}
+
+	output, err := command.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(string(output), "\x00"), nil
+}
+
+// FindFilesIn returns the files of the given commit.
+func FindFilesIn(revision string) ([]string, error) {
+	command := &GitCommand{Args: []string{"ls-tree", "-r", "-z", "--name-only", "--full-tree", revision}}
+
+	output, err := command.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(string(output), "\x00"), nil
+}
+
+// FindFilesBetween returns the list of files changed between the given refs,
+// or an error if they are not valid refs.
+func FindFilesBetween(fromRef string, toRef string) ([]string, error) {
+	command := &GitCommand{Args: []string{"diff", "--name-only", "-z", fromRef, toRef, "--"}}
+
+	output, err := command.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(string(output), "\x00"), nil
+}
+
+// CurrentBranch returns the name of the current branch, or an empty string if HEAD is detached.
+func CurrentBranch() string {
+	command := &GitCommand{Args: []string{"symbolic-ref", "--quiet", "--short", "HEAD"}}
+
+	return strings.TrimSpace(string(command.RunAndGetOutput()))
+}
+
+// CurrentCommit returns the sha of HEAD, or an empty string if there isn't any commit.
+func CurrentCommit() string {
+	command := &GitCommand{Args: []string{"rev-parse", "--verify", "--quiet", "HEAD"}}
+
+	return strings.TrimSpace(string(command.RunAndGetOutput()))
+}
+
 // GitDiff runs the git-diff command
 func GitDiff(options ...string) []string {
 	command := &GitCommand{Args: append([]string{"diff"}, options...)}
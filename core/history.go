@@ -0,0 +1,204 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// History is the append-only log of the runs of capn-hook.
+type History struct {
+	Path string
+}
+
+// HistoryEntry is a run of a hook recorded in the history.
+type HistoryEntry struct {
+	HookName  string            `json:"hook_name"`
+	Commit    string            `json:"commit,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Duration  float64           `json:"duration"`
+	Commands  []*HistoryCommand `json:"commands"`
+}
+
+// HistoryCommand is a command run by a hook recorded in the history.
+type HistoryCommand struct {
+	Hook     string  `json:"hook,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Run      string  `json:"run"`
+	Command  string  `json:"command"`
+	Status   string  `json:"status"`
+	ExitCode int     `json:"exit_code"`
+	Duration float64 `json:"duration"`
+}
+
+// HistoryStats are the aggregated statistics of a command in the history.
+type HistoryStats struct {
+	HookName  string
+	Command   string
+	Runs      int
+	Failures  int
+	P50       time.Duration
+	P95       time.Duration
+	durations []time.Duration
+}
+
+// OpenHistory returns the history stored in the GITDIR.
+func OpenHistory() (*History, error) {
+	gitDir, err := FindGitDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &History{Path: filepath.Join(gitDir, "capn-hook", "history.jsonl")}, nil
+}
+
+// NewHistoryEntry returns the history entry of a run of the given hook.
+func NewHistoryEntry(hookName string, start time.Time, results []*Result) *HistoryEntry {
+	entry := &HistoryEntry{
+		HookName:  hookName,
+		Commit:    CurrentCommit(),
+		Timestamp: start,
+		Duration:  seconds(time.Since(start)),
+		Commands:  []*HistoryCommand{},
+	}
+
+	for _, result := range results {
+		entry.Commands = append(entry.Commands, &HistoryCommand{
+			Hook:     result.Hook,
+			Name:     result.Name,
+			Run:      result.Run,
+			Command:  result.Command,
+			Status:   result.Status,
+			ExitCode: result.ExitCode,
+			Duration: seconds(result.Duration),
+		})
+	}
+
+	return entry
+}
+
+// Append adds the entry at the end of the history.
+func (history *History) Append(entry *HistoryEntry) error {
+	err := os.MkdirAll(filepath.Dir(history.Path), 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(history.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// Entries returns all the entries of the history, skipping the invalid ones.
+func (history *History) Entries() ([]*HistoryEntry, error) {
+	entries := []*HistoryEntry{}
+
+	file, err := os.Open(history.Path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		entry := &HistoryEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Label returns the name of the command, or the command as written in the manifest if it doesn't have one.
+func (command *HistoryCommand) Label() string {
+	if command.Name != "" {
+		return command.Name
+	}
+
+	return command.Run
+}
+
+// ComputeHistoryStats aggregates the commands of the entries by hook name and command.
+func ComputeHistoryStats(entries []*HistoryEntry) []*HistoryStats {
+	statsByCommand := map[string]*HistoryStats{}
+	allStats := []*HistoryStats{}
+	for _, entry := range entries {
+		for _, command := range entry.Commands {
+			if command.Status != ResultPassed && command.Status != ResultFailed {
+				continue
+			}
+
+			key := entry.HookName + "\x00" + command.Label()
+			stats, ok := statsByCommand[key]
+			if !ok {
+				stats = &HistoryStats{HookName: entry.HookName, Command: command.Label()}
+				statsByCommand[key] = stats
+				allStats = append(allStats, stats)
+			}
+
+			stats.Runs++
+			if command.Status == ResultFailed {
+				stats.Failures++
+			}
+			stats.durations = append(stats.durations, time.Duration(command.Duration*float64(time.Second)))
+		}
+	}
+
+	for _, stats := range allStats {
+		sort.Slice(stats.durations, func(i, j int) bool { return stats.durations[i] < stats.durations[j] })
+		stats.P50 = percentile(stats.durations, 50)
+		stats.P95 = percentile(stats.durations, 95)
+	}
+
+	sort.SliceStable(allStats, func(i, j int) bool {
+		if allStats[i].HookName != allStats[j].HookName {
+			return allStats[i].HookName < allStats[j].HookName
+		}
+		return allStats[i].Command < allStats[j].Command
+	})
+
+	return allStats
+}
+
+// FailureRate returns the fraction of runs that failed.
+func (stats *HistoryStats) FailureRate() float64 {
+	if stats.Runs == 0 {
+		return 0
+	}
+
+	return float64(stats.Failures) / float64(stats.Runs)
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
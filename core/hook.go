@@ -1,13 +1,9 @@
 package core
 
 import (
-	"bytes"
 	"fmt"
-	"io"
-	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
+	"sort"
 )
 
 var (
@@ -37,25 +33,70 @@ var (
 
 	// PreAutoGCName is the name of the pre auto GC hook.
 	PreAutoGCName = "pre-auto-gc"
+
+	// PreReceiveName is the name of the pre receive hook, run by the server.
+	PreReceiveName = "pre-receive"
+
+	// UpdateName is the name of the update hook, run by the server for every ref.
+	UpdateName = "update"
 )
 
 var (
 	// SupportedHooks is the list of supported hooks.
 	SupportedHooks = []string{
 		PreCommitName, CommitMsg, PostReceiveName, PrepareCommitMsgName, PostCheckoutName, PostCommitName, PostMergeName, PrePushName, PreAutoGCName,
+		PreReceiveName, UpdateName,
 	}
 )
 
 // Hook represents a hook to run
 type Hook struct {
-	Pattern    string   `yaml:"pattern,omitempty"`
-	Run        []string `yaml:"run"`
-	Required   bool     `yaml:"required,omitempty"`
-	WorkingDir string   `yaml:"working_dir,omitempty"`
+	Name       string     `yaml:"name,omitempty"`
+	ID         string     `yaml:"id,omitempty"`
+	If         *Condition `yaml:"if,omitempty"`
+	Pattern    string     `yaml:"pattern,omitempty"`
+	Types      []string   `yaml:"types,omitempty"`
+	Run        []*Command `yaml:"run"`
+	Required   bool       `yaml:"required,omitempty"`
+	WorkingDir string     `yaml:"working_dir,omitempty"`
+	Cache      bool       `yaml:"cache,omitempty"`
+	PerCommit  bool       `yaml:"per_commit,omitempty"`
+
+	Env     map[string]string `yaml:"env,omitempty"`
+	EnvFile string            `yaml:"env_file,omitempty"`
+
+	ChangedLinesOnly bool   `yaml:"changed_lines_only,omitempty"`
+	OnFailure        string `yaml:"on_failure,omitempty"`
+	OnFailureDefault string `yaml:"on_failure_default,omitempty"`
+}
+
+// Identifier returns the id of the hook, or its name if it doesn't have one.
+func (hook *Hook) Identifier() string {
+	if hook.ID != "" {
+		return hook.ID
+	}
+
+	return hook.Name
+}
+
+// Match returns true if the file is matched by this hook's pattern and types.
+// The types are found in the given source, nil for the working tree.
+func (hook *Hook) Match(filename string, source *FileSource) (bool, error) {
+	ok, err := hook.MatchPattern(filename)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	return hook.MatchTypes(filename, source)
 }
 
-// Match returns true if the file is matched by this hook.
-func (hook *Hook) Match(filename string) (bool, error) {
+// MatchPattern returns true if the file is matched by this hook's pattern.
+// A hook without pattern matches every file.
+func (hook *Hook) MatchPattern(filename string) (bool, error) {
+	if hook.Pattern == "" {
+		return true, nil
+	}
+
 	ok, err := filepath.Match(hook.Pattern, filename)
 	if err != nil {
 		return false, err
@@ -68,92 +109,175 @@ func (hook *Hook) Match(filename string) (bool, error) {
 	return filepath.Match(hook.Pattern, filepath.Base(filename))
 }
 
-// Filter filters the given files using the hook's pattern
-func (hook *Hook) Filter(files []string) []string {
+// MatchTypes returns true if the file of the source has any of this hook's types.
+// A hook without types matches every file.
+func (hook *Hook) MatchTypes(filename string, source *FileSource) (bool, error) {
+	if len(hook.Types) == 0 {
+		return true, nil
+	}
+
+	fileTypes, err := source.FileTypes(filename)
+	if err != nil {
+		return false, err
+	}
+
+	for _, fileType := range hook.Types {
+		if containsString(fileTypes, fileType) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// HasFileFilter returns true if the hook only runs for some files.
+func (hook *Hook) HasFileFilter() bool {
+	return hook.Pattern != "" || len(hook.Types) > 0
+}
+
+// RejectedFile is a file that wasn't selected by a hook.
+type RejectedFile struct {
+	File   string
+	Reason string
+	Err    error
+}
+
+// Filter filters the given files of the source using the hook's pattern and types
+func (hook *Hook) Filter(files []string, source *FileSource) []string {
+	filteredFiles, rejectedFiles := hook.Explain(files, source)
+	for _, rejected := range rejectedFiles {
+		if rejected.Err != nil {
+			Out.Errorf("Error while matching file name %s with pattern %s and types %v: %s\n", rejected.File, hook.Pattern, hook.Types, rejected.Err)
+		}
+	}
+
+	return filteredFiles
+}
+
+// Explain filters the given files like Filter and also returns the rejected files with the reason why.
+// Files that are not in the source, nil for the working tree, are rejected.
+func (hook *Hook) Explain(files []string, source *FileSource) ([]string, []*RejectedFile) {
 	filteredFiles := []string{}
+	rejectedFiles := []*RejectedFile{}
+	reject := func(file string, reason string, err error) {
+		rejectedFiles = append(rejectedFiles, &RejectedFile{File: file, Reason: reason, Err: err})
+	}
+
+	seen := map[string]bool{}
 	for _, file := range files {
-		if file == "" {
-			continue // empty entry
+		if file == "" || seen[file] {
+			continue // empty or repeated entry
+		}
+		seen[file] = true
+
+		if !source.Exists(file) {
+			reject(file, source.missingReason(), nil)
+			continue
 		}
 
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			continue // file doesn't exist
+		ok, err := hook.MatchPattern(file)
+		if err != nil {
+			reject(file, err.Error(), err)
+			continue
 		}
 
-		ok, err := hook.Match(file)
+		if !ok {
+			reject(file, fmt.Sprintf("doesn't match pattern %s", hook.Pattern), nil)
+			continue
+		}
 
+		ok, err = hook.MatchTypes(file, source)
 		if err != nil {
-			fmt.Printf("Error while matching file name %s with pattern %s: %s", file, hook.Pattern, err)
+			reject(file, err.Error(), err)
 			continue
 		}
 
-		if ok {
-			filteredFiles = append(filteredFiles, file)
+		if !ok {
+			fileTypes, _ := source.FileTypes(file)
+			reject(file, fmt.Sprintf("types %v don't include any of %v", fileTypes, hook.Types), nil)
+			continue
 		}
 
+		filteredFiles = append(filteredFiles, file)
 	}
 
-	return filteredFiles
+	return filteredFiles, rejectedFiles
 }
 
-// RunCommand runs the given command
-func (hook *Hook) RunCommand(workingDir string, command string, input string) {
-	if HasAnyTemplateVariables(command) {
-		return
+// ResolveWorkingDir returns the directory where the commands of the hook run.
+func (hook *Hook) ResolveWorkingDir(baseDir string) string {
+	if hook.WorkingDir == "" {
+		return baseDir
 	}
 
-	fmt.Printf("# Running %s\n", command)
-	cmdParts := strings.Split(command, " ")
-
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	cmd.Dir = workingDir
-
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		fmt.Printf("Error while running command: %s\n", err)
-		os.Exit(1)
+	if filepath.IsAbs(hook.WorkingDir) {
+		return hook.WorkingDir
 	}
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 
-	io.Copy(stdin, bytes.NewBufferString(input))
-
-	err = cmd.Run()
-	if err != nil {
-		fmt.Printf("Error while running command: %s\n", err)
-		if hook.Required {
-			os.Exit(1)
-		}
-	}
+	return filepath.Join(baseDir, hook.WorkingDir)
 }
 
-// RunCommands runs the command associated with this hook.
-func (hook *Hook) RunCommands(workingDir string, input string, args []string) {
-	for _, command := range hook.Run {
-		files := FindModifiedFiles()
-		filteredFiles := hook.Filter(files)
+// ExpandedCommand is a command line ready to run and the files it runs on.
+type ExpandedCommand struct {
+	Command *Command
+	Line    string
+	Files   []string
+}
 
-		if len(filteredFiles) == 0 && hook.Pattern != "" {
-			// nothing to do here
-			return
+// ExpandCommand returns the command lines to run for the given command, files and
+// arguments. Built-in checks run once over all the files, if any are needed.
+func (hook *Hook) ExpandCommand(command *Command, files []string, args []string) []*ExpandedCommand {
+	if command.Builtin != "" {
+		if len(files) == 0 && command.ChecksFiles() {
+			return []*ExpandedCommand{}
 		}
 
-		commandsToRun := map[string]bool{}
-		filesInString := EscapeStringArray(filteredFiles)
-		argsInString := EscapeStringArray(args)
-		for _, fileName := range filteredFiles {
-			tmpl := Template{Text: command}
-			tmpl.Apply(Vars{"files": filesInString})
-			tmpl.Apply(Vars{"file": fileName})
-			tmpl.Apply(Vars{"args": argsInString})
+		return []*ExpandedCommand{{Command: command, Line: command.Line(), Files: files}}
+	}
 
-			commandsToRun[tmpl.Text] = true
+	filesInString := EscapeStringArray(files)
+	argsInString := EscapeStringArray(args)
+	perFile := HasTemplateVariable(command.Run, "file")
+
+	commandsToRun := map[string]*ExpandedCommand{}
+	for _, fileName := range files {
+		tmpl := Template{Text: command.Run}
+		tmpl.Apply(Vars{"files": filesInString})
+		tmpl.Apply(Vars{"file": fileName})
+		tmpl.Apply(Vars{"args": argsInString})
+
+		if expanded, ok := commandsToRun[tmpl.Text]; ok {
+			if perFile {
+				expanded.Files = append(expanded.Files, fileName)
+			}
+			continue
 		}
 
-		for commandToRun := range commandsToRun {
-			hook.RunCommand(workingDir, commandToRun, input)
+		expanded := &ExpandedCommand{Command: command, Line: tmpl.Text, Files: files}
+		if perFile {
+			expanded.Files = []string{fileName}
 		}
+		commandsToRun[tmpl.Text] = expanded
+	}
+
+	commandLines := make([]string, 0, len(commandsToRun))
+	for commandLine := range commandsToRun {
+		commandLines = append(commandLines, commandLine)
+	}
+	sort.Strings(commandLines)
+
+	expandedCommands := make([]*ExpandedCommand, 0, len(commandLines))
+	for _, commandLine := range commandLines {
+		expandedCommands = append(expandedCommands, commandsToRun[commandLine])
 	}
+
+	return expandedCommands
+}
+
+// Environment returns the given environment with the variables of the hook.
+// The env file is relative to the given directory.
+func (hook *Hook) Environment(env []string, baseDir string) ([]string, error) {
+	return applyEnv(env, baseDir, hook.EnvFile, hook.Env)
 }
 
 // IsSupportedHook returns true if the given hook is supported.
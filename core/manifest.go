@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -30,6 +31,13 @@ type Manifest struct {
 	PostMerge        []*Hook `yaml:"post-merge,omitempty"`
 	PrePush          []*Hook `yaml:"pre-push,omitempty"`
 	PreAutoGC        []*Hook `yaml:"pre-auto-gc,omitempty"`
+	PreReceive       []*Hook `yaml:"pre-receive,omitempty"`
+	Update           []*Hook `yaml:"update,omitempty"`
+
+	WarnSlowerThan string            `yaml:"warn_slower_than,omitempty"`
+	Env            map[string]string `yaml:"env,omitempty"`
+	EnvFile        string            `yaml:"env_file,omitempty"`
+	Requires       []*Requirement    `yaml:"requires,omitempty"`
 
 	Path string `yaml:"-"`
 }
@@ -99,11 +107,81 @@ func (manifest *Manifest) Hooks(name string) []*Hook {
 		{
 			return manifest.CommitMsg
 		}
+	case PreReceiveName:
+		{
+			return manifest.PreReceive
+		}
+	case UpdateName:
+		{
+			return manifest.Update
+		}
 	}
 
 	return nil
 }
 
+// SlowThreshold returns the duration after which a hook is considered slow, or 0 if there isn't any.
+func (manifest *Manifest) SlowThreshold() (time.Duration, error) {
+	if manifest.WarnSlowerThan == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(manifest.WarnSlowerThan)
+}
+
+// Environment returns the given environment with the variables of the manifest.
+func (manifest *Manifest) Environment(env []string) ([]string, error) {
+	return applyEnv(env, filepath.Dir(manifest.Path), manifest.EnvFile, manifest.Env)
+}
+
+// RequirementFailure is a requirement that isn't met.
+type RequirementFailure struct {
+	Requirement *Requirement
+	Err         error
+}
+
+// CheckRequirements returns the requirements of the manifest that aren't met.
+func (manifest *Manifest) CheckRequirements(env []string) []*RequirementFailure {
+	failures := []*RequirementFailure{}
+	for _, requirement := range manifest.Requires {
+		if err := requirement.Check(env); err != nil {
+			failures = append(failures, &RequirementFailure{Requirement: requirement, Err: err})
+		}
+	}
+
+	return failures
+}
+
+// PrintRequirementFailures prints the requirements that aren't met and how to install them.
+func PrintRequirementFailures(failures []*RequirementFailure) {
+	Out.Errorf("Some tools required by %s are missing or outdated:\n", DefaultManifestFileName)
+	for _, failure := range failures {
+		Out.Printf("  %s %s\n", Out.Colorize(colorRed, "✗"), failure.Err)
+		if failure.Requirement.Install != "" {
+			Out.Printf("      install with: %s\n", failure.Requirement.Install)
+		}
+	}
+}
+
+// HasStep returns true if any hook or command in the manifest has the given name or id.
+func (manifest *Manifest) HasStep(identifier string) bool {
+	for _, hookName := range SupportedHooks {
+		for _, hook := range manifest.Hooks(hookName) {
+			if hook.Identifier() == identifier {
+				return true
+			}
+
+			for _, command := range hook.Run {
+				if command.Identifier() == identifier {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
 // ToByteArray returns the manifest encoded
 func (manifest *Manifest) ToByteArray() []byte {
 	data, err := yaml.Marshal(manifest)
@@ -133,7 +211,7 @@ func findManifestIn(path string, depth int) (*Manifest, error) {
 		fileName := filepath.Base(match)
 
 		if fileName == DefaultManifestFileName {
-			return LoadManifest(fileName)
+			return LoadManifest(match)
 		}
 	}
 
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// QuietLevel only prints failures and errors.
+	QuietLevel = iota
+
+	// NormalLevel prints a line for every command and the output of the failed ones.
+	NormalLevel
+
+	// VerboseLevel also prints the output of every command while it runs.
+	VerboseLevel
+)
+
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBold   = "\033[1m"
+	colorReset  = "\033[0m"
+)
+
+var (
+	// Out is the output used to print all the messages.
+	Out = NewOutput(os.Stdout)
+)
+
+// Output prints the messages of capn-hook with the configured level and colors.
+// Errors are printed to ErrWriter.
+type Output struct {
+	Writer     io.Writer
+	ErrWriter  io.Writer
+	Level      int
+	Color      bool
+	ErrorColor bool
+}
+
+// NewOutput returns an output for the given file, with the errors printed to
+// stderr. Colors are enabled when the file is a terminal and NO_COLOR is not set.
+func NewOutput(file *os.File) *Output {
+	_, noColor := os.LookupEnv("NO_COLOR")
+
+	return &Output{
+		Writer:     file,
+		ErrWriter:  os.Stderr,
+		Level:      NormalLevel,
+		Color:      IsTerminal(file) && !noColor,
+		ErrorColor: IsTerminal(os.Stderr) && !noColor,
+	}
+}
+
+// IsTerminal returns true if the file is a terminal.
+func IsTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return (stat.Mode() & os.ModeCharDevice) != 0
+}
+
+// IsQuiet returns true if only failures and errors are printed.
+func (output *Output) IsQuiet() bool {
+	return output.Level <= QuietLevel
+}
+
+// IsVerbose returns true if the output of every command is printed.
+func (output *Output) IsVerbose() bool {
+	return output.Level >= VerboseLevel
+}
+
+// Printf prints the message regardless of the level.
+func (output *Output) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(output.Writer, format, args...)
+}
+
+// Println prints the values regardless of the level.
+func (output *Output) Println(args ...interface{}) {
+	fmt.Fprintln(output.Writer, args...)
+}
+
+// Infof prints the message unless the output is quiet.
+func (output *Output) Infof(format string, args ...interface{}) {
+	if !output.IsQuiet() {
+		output.Printf(format, args...)
+	}
+}
+
+// Verbosef prints the message only if the output is verbose.
+func (output *Output) Verbosef(format string, args ...interface{}) {
+	if output.IsVerbose() {
+		output.Printf(format, args...)
+	}
+}
+
+// Errorf prints the error message to ErrWriter regardless of the level.
+func (output *Output) Errorf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if output.ErrorColor {
+		message = colorRed + message + colorReset
+	}
+
+	fmt.Fprint(output.ErrWriter, message)
+}
+
+// Passed prints the marker of a command that succeeded.
+func (output *Output) Passed(label string, duration time.Duration) {
+	output.Infof("%s %s%s\n", output.Colorize(colorGreen, "✓"), label, output.duration(duration))
+}
+
+// Failed prints the marker of a command that failed and its output, unless it was already printed.
+func (output *Output) Failed(label string, duration time.Duration, commandOutput string) {
+	output.Printf("%s %s%s\n", output.Colorize(colorRed, "✗"), label, output.duration(duration))
+	if output.IsVerbose() || commandOutput == "" {
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(commandOutput, "\n"), "\n") {
+		output.Printf("    %s\n", line)
+	}
+}
+
+// Skipped prints the marker of a command that didn't run.
+func (output *Output) Skipped(label string, reason string) {
+	output.Infof("%s %s %s\n", output.Colorize(colorYellow, "-"), label, output.Colorize(colorYellow, "("+reason+")"))
+}
+
+// Colorize wraps the text with the given color when colors are enabled.
+func (output *Output) Colorize(color string, text string) string {
+	if !output.Color {
+		return text
+	}
+
+	return color + text + colorReset
+}
+
+// duration returns the formatted duration, or nothing if it is zero.
+func (output *Output) duration(duration time.Duration) string {
+	if duration == 0 {
+		return ""
+	}
+
+	return " " + output.Colorize(colorBold, duration.Round(time.Millisecond).String())
+}
+
+// severity returns the padded and colored severity of a diagnostic.
+func (output *Output) severity(severity string) string {
+	padded := fmt.Sprintf("%-7s", severity)
+	switch severity {
+	case DiagnosticError:
+		return output.Colorize(colorRed, padded)
+	case DiagnosticWarning:
+		return output.Colorize(colorYellow, padded)
+	}
+
+	return padded
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	// OnFailurePrompt asks whether to continue when a non-required hook fails.
+	OnFailurePrompt = "prompt"
+
+	// OnFailureContinue continues when a non-required hook fails.
+	OnFailureContinue = "continue"
+
+	// OnFailureAbort aborts when a non-required hook fails.
+	OnFailureAbort = "abort"
+
+	// TTYPath is the path of the terminal used to prompt, git redirects the standard input of the hooks.
+	TTYPath = "/dev/tty"
+
+	errNoAnswer = errors.New("no answer")
+)
+
+// ShouldContinue decides whether to continue after the failure of the given
+// result of a non-required hook, prompting in the terminal if configured.
+func (hook *Hook) ShouldContinue(result *Result) bool {
+	if hook.OnFailure == OnFailureAbort {
+		return false
+	}
+
+	if hook.OnFailure != OnFailurePrompt {
+		return true
+	}
+
+	answer, err := AskToContinue(result)
+	if err != nil {
+		return hook.OnFailureDefault != OnFailureAbort
+	}
+
+	return answer
+}
+
+// AskToContinue asks in the terminal whether to continue after the failure of the result.
+func AskToContinue(result *Result) (bool, error) {
+	tty, err := os.OpenFile(TTYPath, os.O_RDWR, 0)
+	if err != nil {
+		return false, err
+	}
+	defer tty.Close()
+
+	reader := bufio.NewReader(tty)
+	for {
+		fmt.Fprintf(tty, "%s failed. Continue anyway? [y/N/show output] ", result.Command)
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			return false, errNoAnswer
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true, nil
+		case "", "n", "no":
+			return false, nil
+		case "s", "show", "show output":
+			fmt.Fprintln(tty, strings.TrimRight(result.Output, "\n"))
+		}
+	}
+}
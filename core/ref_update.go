@@ -0,0 +1,152 @@
+package core
+
+import (
+	"os/exec"
+	"strings"
+)
+
+var (
+	// ZeroSHA is the object name git uses for a ref that doesn't exist.
+	ZeroSHA = "0000000000000000000000000000000000000000"
+)
+
+// RefUpdate is the update of a ref of the remote repository by a push.
+type RefUpdate struct {
+	LocalRef string
+	Ref      string
+	Old      string
+	New      string
+}
+
+// ParsePrePushInput parses the `<local ref> <local sha> <remote ref> <remote sha>`
+// lines that git passes to the pre-push hook.
+func ParsePrePushInput(input string) []*RefUpdate {
+	updates := []*RefUpdate{}
+	for _, line := range strings.Split(input, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+
+		updates = append(updates, &RefUpdate{LocalRef: fields[0], New: fields[1], Ref: fields[2], Old: fields[3]})
+	}
+
+	return updates
+}
+
+// ParseReceiveInput parses the `<old sha> <new sha> <ref>` lines that git
+// passes to the pre-receive hook.
+func ParseReceiveInput(input string) []*RefUpdate {
+	updates := []*RefUpdate{}
+	for _, line := range strings.Split(input, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		updates = append(updates, &RefUpdate{Old: fields[0], New: fields[1], Ref: fields[2]})
+	}
+
+	return updates
+}
+
+// ParseUpdateArgs parses the `<ref> <old sha> <new sha>` arguments of the update hook.
+func ParseUpdateArgs(args []string) []*RefUpdate {
+	if len(args) != 3 {
+		return []*RefUpdate{}
+	}
+
+	return []*RefUpdate{{Ref: args[0], Old: args[1], New: args[2]}}
+}
+
+// Branch returns the name of the updated branch, or an empty string if the ref is not a branch.
+func (update *RefUpdate) Branch() string {
+	if !strings.HasPrefix(update.Ref, "refs/heads/") {
+		return ""
+	}
+
+	return strings.TrimPrefix(update.Ref, "refs/heads/")
+}
+
+// Commit returns the new commit of the ref, or the old one if the ref is deleted.
+func (update *RefUpdate) Commit() string {
+	if update.IsDeletion() {
+		return update.Old
+	}
+
+	return update.New
+}
+
+// IsCreation returns true if the ref didn't exist before the update.
+func (update *RefUpdate) IsCreation() bool {
+	return isZeroSHA(update.Old)
+}
+
+// IsDeletion returns true if the update deletes the ref.
+func (update *RefUpdate) IsDeletion() bool {
+	return isZeroSHA(update.New)
+}
+
+// IsFastForward returns true if the old commit is an ancestor of the new one.
+// It's false when the old commit is not available locally.
+func (update *RefUpdate) IsFastForward() bool {
+	if update.IsCreation() || update.IsDeletion() {
+		return true
+	}
+
+	return exec.Command("git", "merge-base", "--is-ancestor", update.Old, update.New).Run() == nil
+}
+
+// Commits returns the commits added by the update, oldest first. The commits of a
+// new ref, or of a ref whose old commit is not available locally, are the ones not
+// reachable from the refs selected by the given rev-list option, e.g. --all.
+func (update *RefUpdate) Commits(excluded string) ([]string, error) {
+	if update.IsDeletion() {
+		return []string{}, nil
+	}
+
+	args := []string{"rev-list", "--reverse", update.New, "--not", excluded}
+	if !update.IsCreation() && exec.Command("git", "cat-file", "-e", update.Old+"^{commit}").Run() == nil {
+		args = []string{"rev-list", "--reverse", update.New, "--not", update.Old}
+	}
+
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(string(output)), nil
+}
+
+// CommitsOf returns the commits added by the updates, oldest first and without
+// repeating the ones added by several updates.
+func CommitsOf(updates []*RefUpdate, excluded string) ([]string, error) {
+	commits := []string{}
+	for _, update := range updates {
+		shas, err := update.Commits(excluded)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sha := range shas {
+			if !containsString(commits, sha) {
+				commits = append(commits, sha)
+			}
+		}
+	}
+
+	return commits, nil
+}
+
+// ShortSHA returns the first 7 characters of the sha.
+func ShortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
+
+func isZeroSHA(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
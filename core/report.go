@@ -0,0 +1,228 @@
+package core
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	// ReportFormats is the list of supported report formats.
+	ReportFormats = []string{"json", "junit", "tap"}
+
+	errUnknownReportFormat = errors.New("unknown report format")
+)
+
+type jsonResult struct {
+	HookName    string        `json:"hook_name"`
+	Hook        string        `json:"hook,omitempty"`
+	Name        string        `json:"name,omitempty"`
+	Command     string        `json:"command"`
+	Pattern     string        `json:"pattern,omitempty"`
+	Commit      string        `json:"commit,omitempty"`
+	Files       []string      `json:"files"`
+	Status      string        `json:"status"`
+	Reason      string        `json:"reason,omitempty"`
+	Required    bool          `json:"required"`
+	ExitCode    int           `json:"exit_code"`
+	Duration    float64       `json:"duration"`
+	Output      string        `json:"output"`
+	Diagnostics []*Diagnostic `json:"diagnostics,omitempty"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name          `xml:"testsuites"`
+	TestSuites []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      float64          `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+
+	duration time.Duration
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// CheckReportFormat returns an error if the format is not one of ReportFormats.
+func CheckReportFormat(format string) error {
+	if !containsString(ReportFormats, format) {
+		return fmt.Errorf("%s %q, the format can be either: %s", errUnknownReportFormat, format, strings.Join(ReportFormats, ", "))
+	}
+
+	return nil
+}
+
+// WriteReport writes the results in the given format.
+func WriteReport(writer io.Writer, format string, results []*Result) error {
+	switch format {
+	case "json":
+		return writeJSONReport(writer, results)
+	case "junit":
+		return writeJUnitReport(writer, results)
+	case "tap":
+		return writeTAPReport(writer, results)
+	}
+
+	return errUnknownReportFormat
+}
+
+func writeJSONReport(writer io.Writer, results []*Result) error {
+	report := make([]*jsonResult, 0, len(results))
+	for _, result := range results {
+		report = append(report, &jsonResult{
+			HookName:    result.HookName,
+			Hook:        result.Hook,
+			Name:        result.Name,
+			Command:     result.Command,
+			Pattern:     result.Pattern,
+			Commit:      result.Commit,
+			Files:       result.Files,
+			Status:      result.Status,
+			Reason:      result.Reason,
+			Required:    result.Required,
+			ExitCode:    result.ExitCode,
+			Duration:    seconds(result.Duration),
+			Output:      result.Output,
+			Diagnostics: result.Diagnostics,
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func writeJUnitReport(writer io.Writer, results []*Result) error {
+	report := &junitTestSuites{}
+	suites := map[string]*junitTestSuite{}
+	for _, result := range results {
+		suite, ok := suites[result.HookName]
+		if !ok {
+			suite = &junitTestSuite{Name: result.HookName}
+			suites[result.HookName] = suite
+			report.TestSuites = append(report.TestSuites, suite)
+		}
+
+		testCase := &junitTestCase{
+			ClassName: result.HookName + "." + resultHookLabel(result),
+			Name:      result.Command,
+			Time:      seconds(result.Duration),
+			SystemOut: result.Output,
+		}
+
+		suite.Tests++
+		suite.duration += result.Duration
+		suite.Time = seconds(suite.duration)
+		switch result.Status {
+		case ResultFailed:
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: fmt.Sprintf("exit code %d", result.ExitCode), Contents: result.Output}
+			testCase.SystemOut = ""
+		case ResultSkipped, ResultNoFiles:
+			suite.Skipped++
+			testCase.Skipped = &junitSkipped{Message: result.Reason}
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
+
+func writeTAPReport(writer io.Writer, results []*Result) error {
+	fmt.Fprintf(writer, "TAP version 13\n1..%d\n", len(results))
+	for i, result := range results {
+		description := fmt.Sprintf("%s %s: %s", result.HookName, resultHookLabel(result), result.Command)
+		switch result.Status {
+		case ResultFailed:
+			fmt.Fprintf(writer, "not ok %d - %s\n", i+1, description)
+		case ResultSkipped, ResultNoFiles:
+			fmt.Fprintf(writer, "ok %d - %s # SKIP %s\n", i+1, description, result.Reason)
+			continue
+		default:
+			fmt.Fprintf(writer, "ok %d - %s\n", i+1, description)
+		}
+
+		fmt.Fprintf(writer, "  ---\n")
+		fmt.Fprintf(writer, "  exit_code: %d\n", result.ExitCode)
+		fmt.Fprintf(writer, "  duration_ms: %d\n", result.Duration.Nanoseconds()/1e6)
+		if len(result.Files) > 0 {
+			fmt.Fprintf(writer, "  files:\n")
+			for _, file := range result.Files {
+				fmt.Fprintf(writer, "    - %q\n", file)
+			}
+		}
+		if result.Output != "" {
+			fmt.Fprintf(writer, "  output: |\n")
+			for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+				fmt.Fprintf(writer, "    %s\n", line)
+			}
+		}
+		_, err := fmt.Fprintf(writer, "  ...\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seconds returns the duration in seconds rounded to milliseconds.
+func seconds(duration time.Duration) float64 {
+	return math.Round(duration.Seconds()*1000) / 1000
+}
+
+// resultHookLabel returns the name of the hook of the result, or its pattern if
+// it doesn't have one, followed by the commit checked when it ran per commit.
+func resultHookLabel(result *Result) string {
+	label := "*"
+	switch {
+	case result.Hook != "":
+		label = result.Hook
+	case result.Pattern != "":
+		label = result.Pattern
+	}
+
+	if result.Commit != "" {
+		label += "@" + ShortSHA(result.Commit)
+	}
+
+	return label
+}
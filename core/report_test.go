@@ -0,0 +1,28 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckReportFormatMatchesWriteReport(t *testing.T) {
+	for _, format := range ReportFormats {
+		if err := CheckReportFormat(format); err != nil {
+			t.Errorf("CheckReportFormat(%q) error = %v", format, err)
+		}
+
+		if err := WriteReport(&bytes.Buffer{}, format, []*Result{}); err != nil {
+			t.Errorf("WriteReport(%q) error = %v", format, err)
+		}
+	}
+
+	for _, format := range []string{"", "xml", "JSON", "sarif"} {
+		if err := CheckReportFormat(format); err == nil {
+			t.Errorf("CheckReportFormat(%q) error = nil, want an error", format)
+		}
+
+		if err := WriteReport(&bytes.Buffer{}, format, []*Result{}); err == nil {
+			t.Errorf("WriteReport(%q) error = nil, want an error", format)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	versionExpr    = regexp.MustCompile(`\d+(?:\.\d+)*`)
+	constraintExpr = regexp.MustCompile(`^(>=|<=|!=|==|=|>|<|\^|~)?\s*v?(\d+(?:\.\d+)*)$`)
+)
+
+// Requirement is a tool needed by the hooks of the manifest.
+type Requirement struct {
+	Name       string `yaml:"name"`
+	VersionCmd string `yaml:"version_cmd,omitempty"`
+	Version    string `yaml:"version,omitempty"`
+	Install    string `yaml:"install,omitempty"`
+}
+
+// Check returns an error if the tool is not installed or its version doesn't satisfy the constraint.
+func (requirement *Requirement) Check(env []string) error {
+	if _, ok := findExecutable(requirement.Name, env); !ok {
+		return fmt.Errorf("%s is not installed", requirement.Name)
+	}
+
+	if requirement.VersionCmd == "" || requirement.Version == "" {
+		return nil
+	}
+
+	cmdParts := strings.Split(requirement.VersionCmd, " ")
+	cmd := exec.Command(lookPath(cmdParts[0], env), cmdParts[1:]...)
+	cmd.Env = env
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("can't get the version of %s: %s", requirement.Name, err)
+	}
+
+	version := versionExpr.FindString(string(output))
+	if version == "" {
+		return fmt.Errorf("can't find the version of %s in %q", requirement.Name, strings.TrimSpace(string(output)))
+	}
+
+	ok, err := SatisfiesConstraint(version, requirement.Version)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("%s %s doesn't satisfy %s", requirement.Name, version, requirement.Version)
+	}
+
+	return nil
+}
+
+// SatisfiesConstraint returns true if the version satisfies all the comma
+// separated clauses of the constraint, e.g. ">=1.2, <2".
+func SatisfiesConstraint(version string, constraint string) (bool, error) {
+	current := parseVersion(version)
+	for _, clause := range strings.Split(constraint, ",") {
+		match := constraintExpr.FindStringSubmatch(strings.TrimSpace(clause))
+		if match == nil {
+			return false, fmt.Errorf("invalid version constraint: %q", clause)
+		}
+
+		operator := match[1]
+		wanted := parseVersion(match[2])
+		cmp := compareVersions(current, wanted)
+
+		var ok bool
+		switch operator {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "!=":
+			ok = cmp != 0
+		case "^":
+			parts := caretParts(wanted)
+			ok = cmp >= 0 && compareVersions(current[:minInt(len(current), parts)], wanted[:parts]) == 0
+		case "~":
+			ok = cmp >= 0 && current[0] == wanted[0] && versionPart(current, 1) == versionPart(wanted, 1)
+		default:
+			ok = compareVersions(current[:minInt(len(current), len(wanted))], wanted) == 0
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// caretParts returns how many parts of the version a ^ constraint keeps, like
+// semver: up to the first one that is not zero, so ^1.2 is >=1.2 <2, ^0.3 is
+// >=0.3 <0.4 and ^0.0.3 is only 0.0.3.
+func caretParts(version []int) int {
+	for i, part := range version {
+		if part != 0 {
+			return i + 1
+		}
+	}
+
+	return len(version)
+}
+
+func parseVersion(version string) []int {
+	parts := []int{}
+	for _, part := range strings.Split(version, ".") {
+		number, _ := strconv.Atoi(part)
+		parts = append(parts, number)
+	}
+
+	return parts
+}
+
+func compareVersions(a []int, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		x, y := versionPart(a, i), versionPart(b, i)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func versionPart(version []int, index int) int {
+	if index < len(version) {
+		return version[index]
+	}
+
+	return 0
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,67 @@
+package core
+
+import "testing"
+
+func TestSatisfiesConstraint(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.2.3", ">=1.2", true},
+		{"1.2.0", ">=1.2", true},
+		{"1.1.9", ">=1.2", false},
+		{"1.2", ">1.2.0", false},
+		{"1.10.0", ">1.9", true},
+		{"1.2.3", "<2", true},
+		{"2.0.0", "<2", false},
+		{"2.0.0", "<=2", true},
+		{"1.2.3", "!=1.2.3", false},
+		{"1.2.4", "!=1.2.3", true},
+		{"1.2.3", "1.2", true},
+		{"1.2.3", "=1.2", true},
+		{"1.2.3", "==1.2.3", true},
+		{"1.3.0", "1.2", false},
+		{"1.2", "=1.2.3", false},
+		{"1.2.3", "v1.2.3", true},
+		{"1.5.0", "^1.2", true},
+		{"2.0.0", "^1.2", false},
+		{"1.1.0", "^1.2", false},
+		{"0.3.5", "^0.3", true},
+		{"0.4.0", "^0.3", false},
+		{"0.2.9", "^0.3", false},
+		{"0.3.1", "^0.3.2", false},
+		{"0.0.3", "^0.0.3", true},
+		{"0.0.4", "^0.0.3", false},
+		{"0.0.9", "^0.0", true},
+		{"0.1.0", "^0.0", false},
+		{"0.9.0", "^0", true},
+		{"1.0.0", "^0", false},
+		{"1.2.9", "~1.2.3", true},
+		{"1.3.0", "~1.2.3", false},
+		{"1.2.2", "~1.2.3", false},
+		{"1.4.0", ">=1.2, <2", true},
+		{"2.1.0", ">=1.2, <2", false},
+		{"1.0.0", ">= 1.0", true},
+	}
+
+	for _, test := range tests {
+		got, err := SatisfiesConstraint(test.version, test.constraint)
+		if err != nil {
+			t.Errorf("SatisfiesConstraint(%q, %q) error = %v", test.version, test.constraint, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("SatisfiesConstraint(%q, %q) = %v, want %v", test.version, test.constraint, got, test.want)
+		}
+	}
+}
+
+func TestSatisfiesConstraintWithInvalidConstraint(t *testing.T) {
+	for _, constraint := range []string{"", "latest", ">=", "=>1.2", "1.x", ">=1.2,"} {
+		if _, err := SatisfiesConstraint("1.2.3", constraint); err == nil {
+			t.Errorf("SatisfiesConstraint(%q) error = nil, want an error", constraint)
+		}
+	}
+}
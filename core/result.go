@@ -0,0 +1,70 @@
+package core
+
+import (
+	"time"
+)
+
+var (
+	// ResultPassed is the status of the commands that succeeded.
+	ResultPassed = "passed"
+
+	// ResultFailed is the status of the commands that failed.
+	ResultFailed = "failed"
+
+	// ResultSkipped is the status of the commands that didn't run.
+	ResultSkipped = "skipped"
+
+	// ResultNoFiles is the status of the commands that didn't run because no file matched.
+	ResultNoFiles = "no files"
+)
+
+// Result is the result of running an expanded command.
+type Result struct {
+	HookName    string
+	Hook        string
+	Name        string
+	Run         string
+	Command     string
+	Pattern     string
+	Commit      string
+	Files       []string
+	Status      string
+	Reason      string
+	Required    bool
+	Aborted     bool
+	ExitCode    int
+	Duration    time.Duration
+	Output      string
+	Diagnostics []*Diagnostic
+}
+
+// Ran returns true if the command ran.
+func (result *Result) Ran() bool {
+	return result.Status == ResultPassed || result.Status == ResultFailed
+}
+
+// Failed returns true if the command failed.
+func (result *Result) Failed() bool {
+	return result.Status == ResultFailed
+}
+
+// Diagnostics returns the diagnostics of all the results.
+func Diagnostics(results []*Result) []*Diagnostic {
+	diagnostics := []*Diagnostic{}
+	for _, result := range results {
+		diagnostics = append(diagnostics, result.Diagnostics...)
+	}
+
+	return diagnostics
+}
+
+// HasRequiredFailure returns true if any required command failed or a failure aborted the run.
+func HasRequiredFailure(results []*Result) bool {
+	for _, result := range results {
+		if result.Failed() && (result.Required || result.Aborted) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,464 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RunOptions are the options used to run the commands of a hook.
+type RunOptions struct {
+	HookName   string
+	WorkingDir string
+	Input      string
+	Args       []string
+	Files      []string
+	Only       []string
+	Skip       []string
+	Cache      *Cache
+	DiffArgs   []string
+	Env        []string
+	RefUpdate  *RefUpdate
+	Commit     *Commit
+	Source     *FileSource
+
+	changedLines ChangedLines
+}
+
+// RunCommand runs the given command with the given environment and returns its combined output.
+func (hook *Hook) RunCommand(workingDir string, command string, input string, env []string) (string, error) {
+	Out.Verbosef("# Running %s\n", command)
+	cmdParts := strings.Split(command, " ")
+
+	cmd := exec.Command(lookPath(cmdParts[0], env), cmdParts[1:]...)
+	cmd.Dir = workingDir
+	cmd.Env = env
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+
+	output := &bytes.Buffer{}
+	var writer io.Writer = output
+	if Out.IsVerbose() {
+		writer = io.MultiWriter(Out.Writer, output)
+	}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	io.Copy(stdin, bytes.NewBufferString(input))
+
+	err = cmd.Run()
+	return output.String(), err
+}
+
+// RunCommands runs the command associated with this hook. It stops after the
+// first failure when the hook is required.
+func (hook *Hook) RunCommands(options *RunOptions) []*Result {
+	results := []*Result{}
+	if !options.RunsHook(hook) {
+		if options.isSkipped(hook.Identifier()) {
+			return hook.notRunResults(options, hook.Run, ResultSkipped, "skipped")
+		}
+		return results
+	}
+
+	if hook.PerCommit && options.Commit == nil {
+		return hook.runPerCommit(options)
+	}
+
+	if hook.If != nil {
+		if ok, reason := hook.If.Evaluate(options.WorkingDir, options.environment(), false); !ok {
+			return hook.notRunResults(options, hook.Run, ResultSkipped, reason)
+		}
+	}
+
+	filteredFiles := hook.Filter(options.Files, options.Source)
+	if len(filteredFiles) == 0 && hook.HasFileFilter() {
+		// nothing to do here
+		return hook.notRunResults(options, hook.Run, ResultNoFiles, "no matching files")
+	}
+
+	workingDir := hook.ResolveWorkingDir(options.WorkingDir)
+	hookEnv, envErr := hook.Environment(options.environment(), options.WorkingDir)
+	if envErr != nil {
+		Out.Errorf("Error while loading the environment: %s\n", envErr)
+	}
+
+	for _, command := range hook.Run {
+		if !options.RunsCommand(hook, command) {
+			if options.isSkipped(command.Identifier()) {
+				results = append(results, hook.notRunResults(options, []*Command{command}, ResultSkipped, "skipped")...)
+			}
+			continue
+		}
+
+		expandedCommands := options.expandCommand(hook, command, filteredFiles)
+		if len(expandedCommands) == 0 {
+			results = append(results, hook.notRunResults(options, []*Command{command}, ResultNoFiles, "no files to expand it")...)
+		}
+
+		for _, expanded := range expandedCommands {
+			if HasAnyTemplateVariables(expanded.Line) {
+				continue
+			}
+
+			result := hook.newResult(options, expanded)
+			results = append(results, result)
+
+			env, err := hookEnv, envErr
+			if err == nil {
+				env, err = command.Environment(hookEnv, options, hook, expanded)
+			}
+
+			cacheKey := ""
+			if err == nil && options.usesCache(hook, command) {
+				cacheKey = options.Cache.Key(workingDir, expanded, env, options.Source)
+				if cacheKey != "" && options.Cache.Has(cacheKey) {
+					result.Status = ResultSkipped
+					result.Reason = "cached"
+					Out.Skipped(expanded.Line, result.Reason)
+					continue
+				}
+			}
+
+			start := time.Now()
+			output := ""
+			if err == nil && command.Builtin != "" {
+				result.Diagnostics, output, err = hook.RunBuiltin(options, expanded, env)
+				if err == nil {
+					err = errorDiagnostics(result.Diagnostics)
+				}
+			} else if err == nil {
+				output, err = hook.RunCommand(workingDir, expanded.Line, options.Input, env)
+				if command.Format != "" {
+					result.Diagnostics = parseCommandDiagnostics(command, options.WorkingDir, workingDir, output)
+				}
+			}
+			result.Duration = time.Since(start)
+			result.Output = output
+			if hook.ChangedLinesOnly && (command.Format != "" || command.Builtin != "") {
+				err = options.ignoreUnchangedLines(result, err)
+			}
+
+			if err != nil {
+				result.Status = ResultFailed
+				result.ExitCode = exitCode(err)
+				if result.ExitCode == -1 {
+					result.Output += fmt.Sprintf("Error while running command: %s\n", err)
+				}
+
+				Out.Failed(expanded.Line, result.Duration, result.Output)
+				if hook.Required {
+					return results
+				}
+
+				if !hook.ShouldContinue(result) {
+					result.Aborted = true
+					return results
+				}
+				continue
+			}
+
+			result.Status = ResultPassed
+			Out.Passed(expanded.Line, result.Duration)
+			if cacheKey != "" {
+				if err := options.Cache.Store(cacheKey, expanded); err != nil {
+					Out.Errorf("Error while storing the cache: %s\n", err)
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+// runPerCommit runs the commands of the hook once for every commit being pushed,
+// on the files changed by the commit.
+func (hook *Hook) runPerCommit(options *RunOptions) []*Result {
+	shas, err := options.commits()
+	if err != nil {
+		Out.Errorf("Error while finding the commits to check: %s\n", err)
+		return hook.notRunResults(options, hook.Run, ResultFailed, err.Error())
+	}
+
+	if len(shas) == 0 {
+		return hook.notRunResults(options, hook.Run, ResultSkipped, "no commits to check")
+	}
+
+	results := []*Result{}
+	for _, sha := range shas {
+		commitOptions, err := options.forCommit(sha)
+		if err != nil {
+			Out.Errorf("Error while reading the commit %s: %s\n", sha, err)
+			return append(results, hook.notRunResults(options, hook.Run, ResultFailed, err.Error())...)
+		}
+
+		commit := commitOptions.Commit
+		Out.Infof("# commit %s %s\n", commit.ShortSHA(), commit.Subject())
+		commitResults := hook.RunCommands(commitOptions)
+		os.Remove(commit.MessageFile)
+
+		results = append(results, commitResults...)
+		if HasRequiredFailure(commitResults) {
+			break
+		}
+	}
+
+	return results
+}
+
+// forCommit loads the commit and returns a copy of the options that runs the
+// commands on the files changed by the commit, read from its tree. The caller
+// removes the message file of the commit.
+func (options *RunOptions) forCommit(sha string) (*RunOptions, error) {
+	commit, err := LoadCommit(sha)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := NewFileSource(commit.SHA, commit.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := commit.WriteMessageFile(); err != nil {
+		return nil, err
+	}
+
+	commitOptions := *options
+	commitOptions.Commit = commit
+	commitOptions.Files = commit.Files
+	commitOptions.Source = source
+	commitOptions.DiffArgs = nil
+	if len(commit.Parents) > 0 {
+		commitOptions.DiffArgs = commit.diffArgs()
+	}
+	commitOptions.changedLines = nil
+
+	return &commitOptions, nil
+}
+
+// commits returns the commits checked by the hooks with per_commit: the ones
+// pushed in pre-push and in the hooks of the server, or the ones between
+// --from-ref and --to-ref.
+func (options *RunOptions) commits() ([]string, error) {
+	updates := []*RefUpdate{}
+	excluded := "--remotes"
+	switch {
+	case options.RefUpdate != nil:
+		updates = append(updates, options.RefUpdate)
+		excluded = "--all"
+	case len(options.DiffArgs) == 2:
+		updates = append(updates, &RefUpdate{Old: options.DiffArgs[0], New: options.DiffArgs[1]})
+	case options.HookName == PrePushName:
+		updates = ParsePrePushInput(options.Input)
+	}
+
+	return CommitsOf(updates, excluded)
+}
+
+// expandCommand expands the command for the files. When the hook runs per
+// commit the variables of the commit are replaced, and the commands that are
+// not run per file also run once for the commits without files.
+func (options *RunOptions) expandCommand(hook *Hook, command *Command, files []string) []*ExpandedCommand {
+	expandedCommands := hook.ExpandCommand(command, files, options.Args)
+	if options.Commit == nil {
+		return expandedCommands
+	}
+
+	if len(expandedCommands) == 0 && command.Builtin == "" && !HasTemplateVariable(command.Run, "file") {
+		tmpl := Template{Text: command.Run}
+		tmpl.Apply(Vars{"files": ""})
+		tmpl.Apply(Vars{"args": EscapeStringArray(options.Args)})
+		expandedCommands = []*ExpandedCommand{{Command: command, Line: tmpl.Text, Files: []string{}}}
+	}
+
+	for _, expanded := range expandedCommands {
+		tmpl := Template{Text: expanded.Line}
+		tmpl.Apply(options.Commit.templateVars())
+		expanded.Line = tmpl.Text
+	}
+
+	return expandedCommands
+}
+
+// usesCache returns true if the result of the command can be cached. The files
+// of a commit are not checked in the working tree, so hooks run per commit are
+// never cached.
+func (options *RunOptions) usesCache(hook *Hook, command *Command) bool {
+	return hook.Cache && options.Cache != nil && command.ChecksFiles() && options.Commit == nil
+}
+
+func (hook *Hook) newResult(options *RunOptions, expanded *ExpandedCommand) *Result {
+	commit := ""
+	if options.Commit != nil {
+		commit = options.Commit.SHA
+	}
+
+	return &Result{
+		HookName: options.HookName,
+		Hook:     hook.Identifier(),
+		Name:     expanded.Command.Identifier(),
+		Run:      expanded.Command.Line(),
+		Command:  expanded.Line,
+		Pattern:  hook.Pattern,
+		Commit:   commit,
+		Files:    expanded.Files,
+		Required: hook.Required,
+	}
+}
+
+// parseCommandDiagnostics parses the output of the command run in workingDir.
+// The paths of the diagnostics are made relative to baseDir, the directory of
+// the manifest, like the files of the hooks and the changed lines.
+func parseCommandDiagnostics(command *Command, baseDir string, workingDir string, output string) []*Diagnostic {
+	diagnostics, err := ParseDiagnostics(command.Format, output)
+	if err != nil {
+		Out.Errorf("Error while parsing the output of %s: %s\n", command.Tool(), err)
+		return nil
+	}
+
+	for _, diagnostic := range diagnostics {
+		diagnostic.Tool = command.Tool()
+		diagnostic.File = rebasePath(diagnostic.File, baseDir, workingDir)
+	}
+
+	return diagnostics
+}
+
+// rebasePath returns the path, relative to workingDir or absolute, relative to
+// baseDir. Paths outside of baseDir are returned unchanged.
+func rebasePath(file string, baseDir string, workingDir string) string {
+	if file == "" {
+		return file
+	}
+
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+
+	relative, err := filepath.Rel(baseDir, path)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, "../") {
+		return file
+	}
+
+	return filepath.ToSlash(relative)
+}
+
+// lookPath finds the executable in the PATH of the given environment, which may
+// differ from the one of the process.
+func lookPath(name string, env []string) string {
+	if env == nil {
+		return name
+	}
+
+	if path, ok := findExecutable(name, env); ok {
+		return path
+	}
+
+	return name
+}
+
+// findExecutable returns the path of the executable in the PATH of the given environment.
+func findExecutable(name string, env []string) (string, bool) {
+	if strings.Contains(name, string(filepath.Separator)) {
+		info, err := os.Stat(name)
+		return name, err == nil && !info.IsDir() && info.Mode()&0111 != 0
+	}
+
+	for _, dir := range filepath.SplitList(LookupEnv(env, "PATH")) {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// environment returns the environment of the hooks, the one of the process by default.
+func (options *RunOptions) environment() []string {
+	if options.Env == nil {
+		return os.Environ()
+	}
+
+	return options.Env
+}
+
+// notRunResults returns the results of commands that didn't run.
+func (hook *Hook) notRunResults(options *RunOptions, commands []*Command, status string, reason string) []*Result {
+	results := []*Result{}
+	for _, command := range commands {
+		if status == ResultSkipped {
+			Out.Skipped(command.Line(), reason)
+		}
+
+		result := hook.newResult(options, &ExpandedCommand{Command: command, Line: command.Line(), Files: []string{}})
+		result.Status = status
+		result.Reason = reason
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// ignoreUnchangedLines drops the diagnostics outside the changed lines. The error
+// of the command is ignored when all its problems are on lines that didn't change,
+// otherwise its output is replaced by the remaining problems.
+func (options *RunOptions) ignoreUnchangedLines(result *Result, err error) error {
+	if options.DiffArgs == nil {
+		return err
+	}
+
+	if options.changedLines == nil {
+		changedLines, diffErr := FindChangedLines(options.WorkingDir, options.DiffArgs...)
+		if diffErr != nil {
+			Out.Errorf("Error while finding the changed lines: %s\n", diffErr)
+			options.DiffArgs = nil
+			return err
+		}
+		options.changedLines = changedLines
+	}
+
+	total := len(result.Diagnostics)
+	result.Diagnostics = options.changedLines.Filter(result.Diagnostics)
+	ignored := total - len(result.Diagnostics)
+	if err == nil || ignored == 0 {
+		return err
+	}
+
+	Out.Infof("Ignoring %d problem(s) outside the changed lines\n", ignored)
+	if len(result.Diagnostics) == 0 {
+		result.Reason = fmt.Sprintf("%d problem(s) outside the changed lines", total)
+		return nil
+	}
+
+	// the output has the ignored problems too, only the remaining ones are printed
+	result.Output = diagnosticsOutput(result.Diagnostics)
+	if _, ok := err.(*problemsError); ok {
+		return errorDiagnostics(result.Diagnostics)
+	}
+
+	return err
+}
+
+// exitCode returns the exit code of a failed command, or -1 if it couldn't run.
+func exitCode(err error) int {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+
+	if _, ok := err.(*problemsError); ok {
+		return 1
+	}
+
+	return -1
+}
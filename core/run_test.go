@@ -0,0 +1,72 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// quietOutput discards the output of the commands until the end of the test.
+func quietOutput(t *testing.T) {
+	previous := Out
+	Out = &Output{Writer: ioutil.Discard, ErrWriter: ioutil.Discard, Level: QuietLevel}
+	t.Cleanup(func() { Out = previous })
+}
+
+func TestChangedLinesOnlyWithWorkingDir(t *testing.T) {
+	dir := newTestRepository(t)
+	quietOutput(t)
+
+	writeTestFile(t, "sub/a.go", "one\ntwo\nthree\n")
+	runGit(t, "add", "-A")
+	runGit(t, "commit", "-q", "-m", "initial")
+
+	// the linter, run in sub, reports the lines 2 and 3 of a.go
+	writeTestFile(t, "sub/lint.sh", "#!/bin/sh\necho 'a.go:2:1: error: changed'\necho 'a.go:3:1: error: unchanged'\nexit 1\n")
+	if err := os.Chmod("sub/lint.sh", 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, "sub/a.go", "one\nTWO\nthree\n")
+	runGit(t, "add", "sub/a.go")
+
+	hook := &Hook{
+		WorkingDir:       "sub",
+		ChangedLinesOnly: true,
+		Required:         true,
+		Run:              []*Command{{Run: "./lint.sh", Format: "gnu"}},
+	}
+	options := &RunOptions{HookName: PreCommitName, WorkingDir: dir, Files: []string{"sub/a.go"}, DiffArgs: []string{"--cached"}}
+
+	results := hook.RunCommands(options)
+	if len(results) != 1 || results[0].Status != ResultFailed {
+		t.Fatalf("RunCommands() = %+v, want a failed result", results)
+	}
+
+	diagnostics := results[0].Diagnostics
+	if len(diagnostics) != 1 || diagnostics[0].File != "sub/a.go" || diagnostics[0].Line != 2 {
+		t.Errorf("Diagnostics = %+v, want the problem of sub/a.go:2", diagnostics)
+	}
+}
+
+func TestRebasePath(t *testing.T) {
+	tests := []struct {
+		file       string
+		workingDir string
+		want       string
+	}{
+		{"a.go", "/repo", "a.go"},
+		{"a.go", "/repo/sub", "sub/a.go"},
+		{"./pkg/a.go", "/repo/sub", "sub/pkg/a.go"},
+		{"../a.go", "/repo/sub", "a.go"},
+		{"/repo/sub/a.go", "/repo/sub", "sub/a.go"},
+		{"/elsewhere/a.go", "/repo", "/elsewhere/a.go"},
+		{"../../a.go", "/repo/sub", "../../a.go"},
+		{"", "/repo/sub", ""},
+	}
+
+	for _, test := range tests {
+		if got := rebasePath(test.file, "/repo", test.workingDir); got != test.want {
+			t.Errorf("rebasePath(%q, %q) = %q, want %q", test.file, test.workingDir, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string      `json:"version"`
+	Schema  string      `json:"$schema"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string       `json:"name"`
+	Rules []*sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string           `json:"ruleId,omitempty"`
+	Level     string           `json:"level"`
+	Message   sarifMessage     `json:"message"`
+	Locations []*sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// WriteSARIF writes the diagnostics of the results as a SARIF 2.1 log with one run per tool.
+func WriteSARIF(writer io.Writer, results []*Result) error {
+	log := &sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []*sarifRun{}}
+
+	runs := map[string]*sarifRun{}
+	rules := map[string]map[string]bool{}
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			run, ok := runs[diagnostic.Tool]
+			if !ok {
+				run = &sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: diagnostic.Tool}}, Results: []*sarifResult{}}
+				runs[diagnostic.Tool] = run
+				rules[diagnostic.Tool] = map[string]bool{}
+				log.Runs = append(log.Runs, run)
+			}
+
+			if diagnostic.Rule != "" && !rules[diagnostic.Tool][diagnostic.Rule] {
+				rules[diagnostic.Tool][diagnostic.Rule] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifRule{ID: diagnostic.Rule})
+			}
+
+			result := &sarifResult{
+				RuleID:  diagnostic.Rule,
+				Level:   diagnostic.Severity,
+				Message: sarifMessage{Text: diagnostic.Message},
+			}
+
+			if diagnostic.File != "" {
+				location := &sarifLocation{
+					PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: diagnostic.File}},
+				}
+				if diagnostic.Line > 0 {
+					location.PhysicalLocation.Region = &sarifRegion{StartLine: diagnostic.Line, StartColumn: diagnostic.Column}
+				}
+				result.Locations = []*sarifLocation{location}
+			}
+
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	for _, run := range log.Runs {
+		sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+			return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
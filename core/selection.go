@@ -0,0 +1,65 @@
+package core
+
+import (
+	"os"
+	"strings"
+)
+
+var (
+	// SkipEnvVar is the environment variable with the comma separated list of steps to skip.
+	SkipEnvVar = "SKIP"
+)
+
+// SkipFromEnv returns the steps listed in the SKIP environment variable.
+func SkipFromEnv() []string {
+	return SplitList(os.Getenv(SkipEnvVar))
+}
+
+// SplitList splits a comma separated list ignoring the empty entries.
+func SplitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// RunsHook returns true if the hook is selected by the --only and --skip options.
+func (options *RunOptions) RunsHook(hook *Hook) bool {
+	if options.isSkipped(hook.Identifier()) {
+		return false
+	}
+
+	if len(options.Only) == 0 || containsString(options.Only, hook.Identifier()) {
+		return true
+	}
+
+	for _, command := range hook.Run {
+		if containsString(options.Only, command.Identifier()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RunsCommand returns true if the command of the given hook is selected by the --only and --skip options.
+func (options *RunOptions) RunsCommand(hook *Hook, command *Command) bool {
+	if options.isSkipped(command.Identifier()) {
+		return false
+	}
+
+	if len(options.Only) == 0 || containsString(options.Only, hook.Identifier()) {
+		return true
+	}
+
+	return containsString(options.Only, command.Identifier())
+}
+
+func (options *RunOptions) isSkipped(identifier string) bool {
+	return identifier != "" && containsString(options.Skip, identifier)
+}
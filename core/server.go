@@ -0,0 +1,312 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ServerManifestConfig is the git config key with the path of the manifest used
+	// by the server instead of the one of the pushed commit.
+	ServerManifestConfig = "capn-hook.manifest"
+)
+
+// IsServerHook returns true if the hook is run by the server when it receives a push.
+func IsServerHook(hookName string) bool {
+	return hookName == PreReceiveName || hookName == UpdateName
+}
+
+// ServerTree is a temporary directory with the files changed by a ref update,
+// extracted from the pushed commit, where the hooks of the server run.
+type ServerTree struct {
+	Dir         string
+	ManifestDir string
+	GitDir      string
+	Update      *RefUpdate
+	Files       []string
+
+	previousDir string
+	previousEnv map[string]*string
+}
+
+// NewServerTree extracts the files changed by the update and the .gitattributes
+// of the pushed commit to a temporary directory.
+func NewServerTree(update *RefUpdate) (*ServerTree, error) {
+	gitDir, err := exec.Command("git", "rev-parse", "--absolute-git-dir").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "capn-hook-")
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &ServerTree{Dir: dir, GitDir: strings.TrimSpace(string(gitDir)), Update: update}
+	files, err := FindFilesChangedBy(update)
+	if err != nil {
+		tree.Close()
+		return nil, err
+	}
+
+	tree.Files, err = tree.Extract(files...)
+	if err != nil {
+		tree.Close()
+		return nil, err
+	}
+
+	if _, err := tree.Extract(".gitattributes"); err != nil {
+		tree.Close()
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// FindFilesChangedBy returns the files added or modified by the commits of the
+// update. The commits of a new ref are the ones not reachable from other refs.
+func FindFilesChangedBy(update *RefUpdate) ([]string, error) {
+	if update.IsDeletion() {
+		return []string{}, nil
+	}
+
+	if !update.IsCreation() {
+		output, err := exec.Command("git", "diff", "--name-only", "-z", "--no-renames", "--diff-filter=d", update.Old, update.New).Output()
+		if err != nil {
+			return nil, err
+		}
+
+		return splitNull(output), nil
+	}
+
+	commits, err := update.Commits("--all")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, commit := range commits {
+		output, err := exec.Command("git", "diff-tree", "--no-commit-id", "--name-only", "-r", "-z", "--root", "--no-renames", "--diff-filter=d", commit).Output()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range splitNull(output) {
+			if !containsString(files, file) {
+				files = append(files, file)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// Extract writes the given files of the pushed commit, or of the deleted one,
+// to the directory and returns the ones that exist in the commit.
+func (tree *ServerTree) Extract(files ...string) ([]string, error) {
+	return extractFiles(tree.Dir, tree.Update.Commit(), files)
+}
+
+// extractFiles writes the given files of the revision to the directory and
+// returns the ones that exist in the revision.
+func extractFiles(dir string, revision string, files []string) ([]string, error) {
+	extracted := []string{}
+	if len(files) == 0 {
+		return extracted, nil
+	}
+
+	args := append([]string{"--literal-pathspecs", "ls-tree", "-r", "-z", "--full-tree", revision, "--"}, files...)
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range splitNull(output) {
+		// <mode> SP <type> SP <object> TAB <file>
+		parts := strings.SplitN(entry, "\t", 2)
+		fields := strings.Fields(parts[0])
+		if len(parts) != 2 || len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+
+		content, err := exec.Command("git", "cat-file", "blob", fields[2]).Output()
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(parts[1]))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+
+		switch fields[0] {
+		case "120000":
+			err = os.Symlink(string(content), path)
+		case "100755":
+			err = ioutil.WriteFile(path, content, 0755)
+		default:
+			err = ioutil.WriteFile(path, content, 0644)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		extracted = append(extracted, parts[1])
+	}
+
+	return extracted, nil
+}
+
+// LoadManifest loads the manifest of the given path, set by the administrator
+// of the server, or else the one of the commit returned by ManifestRevision.
+// The manifest of the pushed commit is never used, otherwise a push could
+// change the checks it has to pass. The manifests of the repository can only
+// use built-in checks in the given hook.
+func (tree *ServerTree) LoadManifest(path string, hookName string) (*Manifest, error) {
+	if path != "" {
+		return LoadManifest(path)
+	}
+
+	revision := tree.ManifestRevision()
+	if revision == "" {
+		return nil, os.ErrNotExist
+	}
+
+	dir, err := ioutil.TempDir("", "capn-hook-manifest-")
+	if err != nil {
+		return nil, err
+	}
+	tree.ManifestDir = dir
+
+	if _, err := extractFiles(dir, revision, []string{DefaultManifestFileName}); err != nil {
+		return nil, err
+	}
+
+	manifest, err := LoadManifest(filepath.Join(dir, DefaultManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRepositoryManifest(manifest, hookName); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// ManifestRevision returns the commit whose manifest is trusted for the update:
+// the old commit of the ref, or the HEAD of the repository when the ref is new.
+// It returns an empty string when there is none.
+func (tree *ServerTree) ManifestRevision() string {
+	if !tree.Update.IsCreation() {
+		return tree.Update.Old
+	}
+
+	output, err := exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD^{commit}").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+// checkRepositoryManifest returns an error if the hooks of the manifest could
+// run something other than built-in checks on the server.
+func checkRepositoryManifest(manifest *Manifest, hookName string) error {
+	hooks := manifest.Hooks(hookName)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	notAllowed := func(what string) error {
+		return fmt.Errorf("%s in the manifest of the repository is not allowed on the server, only built-in checks are; set %s to use a manifest of the server", what, ServerManifestConfig)
+	}
+
+	switch {
+	case len(manifest.Env) > 0 || manifest.EnvFile != "":
+		return notAllowed("env")
+	case len(manifest.Requires) > 0:
+		return notAllowed("requires")
+	}
+
+	for _, hook := range hooks {
+		switch {
+		case len(hook.Env) > 0 || hook.EnvFile != "":
+			return notAllowed("env")
+		case hook.If != nil && hook.If.RunIf != "":
+			return notAllowed("run_if")
+		}
+
+		for _, command := range hook.Run {
+			switch {
+			case command.Builtin == "":
+				return notAllowed(fmt.Sprintf("the command %q", command.Run))
+			case len(command.Env) > 0:
+				return notAllowed("env")
+			case command.VersionCmd != "":
+				return notAllowed("version_cmd")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Enter makes the directory the working directory and the work tree of the
+// repository, so the hooks run in it as in a clone.
+func (tree *ServerTree) Enter() error {
+	previousDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	tree.previousDir = previousDir
+
+	tree.previousEnv = map[string]*string{}
+	for name, value := range map[string]string{"GIT_DIR": tree.GitDir, "GIT_WORK_TREE": tree.Dir} {
+		if previous, ok := os.LookupEnv(name); ok {
+			tree.previousEnv[name] = &previous
+		} else {
+			tree.previousEnv[name] = nil
+		}
+		os.Setenv(name, value)
+	}
+
+	return os.Chdir(tree.Dir)
+}
+
+// Close restores the working directory and the environment and removes the directory.
+func (tree *ServerTree) Close() error {
+	if tree.previousDir != "" {
+		os.Chdir(tree.previousDir)
+	}
+
+	for name, value := range tree.previousEnv {
+		if value == nil {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, *value)
+		}
+	}
+
+	if tree.ManifestDir != "" {
+		os.RemoveAll(tree.ManifestDir)
+	}
+
+	return os.RemoveAll(tree.Dir)
+}
+
+func splitNull(output []byte) []string {
+	entries := []string{}
+	for _, entry := range bytes.Split(output, []byte{0}) {
+		if len(entry) > 0 {
+			entries = append(entries, string(entry))
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestCheckRepositoryManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{"built-in checks", "pre-receive:\n- run:\n  - builtin: secrets\n  - builtin: protected-branch", ""},
+		{"no hooks for the server", "env: {A: b}\nrequires: [{name: make}]\npre-commit:\n- run:\n  - run: make", ""},
+		{"hooks of another server hook", "update:\n- run:\n  - run: make", ""},
+		{"command", "pre-receive:\n- run:\n  - run: make lint", `the command "make lint"`},
+		{"manifest env", "env: {A: b}\npre-receive:\n- run:\n  - builtin: secrets", "env"},
+		{"manifest env_file", "env_file: .env\npre-receive:\n- run:\n  - builtin: secrets", "env"},
+		{"requires", "requires: [{name: make}]\npre-receive:\n- run:\n  - builtin: secrets", "requires"},
+		{"hook env", "pre-receive:\n- env: {A: b}\n  run:\n  - builtin: secrets", "env"},
+		{"hook env_file", "pre-receive:\n- env_file: .env\n  run:\n  - builtin: secrets", "env"},
+		{"run_if", "pre-receive:\n- if: {run_if: 'true'}\n  run:\n  - builtin: secrets", "run_if"},
+		{"command env", "pre-receive:\n- run:\n  - builtin: secrets\n    env: {A: b}", "env"},
+		{"version_cmd", "pre-receive:\n- run:\n  - builtin: secrets\n    version_cmd: make --version", "version_cmd"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manifest := &Manifest{}
+			if err := yaml.UnmarshalStrict([]byte(test.manifest), manifest); err != nil {
+				t.Fatal(err)
+			}
+
+			err := checkRepositoryManifest(manifest, PreReceiveName)
+			switch {
+			case test.want == "" && err != nil:
+				t.Errorf("checkRepositoryManifest() error = %v, want nil", err)
+			case test.want != "" && (err == nil || !strings.HasPrefix(err.Error(), test.want+" in the manifest")):
+				t.Errorf("checkRepositoryManifest() error = %v, want %q not allowed", err, test.want)
+			}
+		})
+	}
+}
+
+func TestServerTreeLoadManifest(t *testing.T) {
+	newTestRepository(t)
+	writeTestFile(t, DefaultManifestFileName, "pre-receive:\n- run:\n  - builtin: secrets\n")
+	runGit(t, "add", "-A")
+	runGit(t, "commit", "-q", "-m", "trusted")
+	trusted := strings.TrimSpace(runGit(t, "rev-parse", "HEAD"))
+
+	// the pushed commit removes the checks
+	writeTestFile(t, DefaultManifestFileName, "pre-receive: []\n")
+	runGit(t, "commit", "-q", "-am", "pushed")
+	pushed := strings.TrimSpace(runGit(t, "rev-parse", "HEAD"))
+	runGit(t, "reset", "-q", "--hard", trusted)
+
+	for _, update := range []*RefUpdate{
+		{Ref: "refs/heads/main", Old: trusted, New: pushed},
+		{Ref: "refs/heads/feature", Old: ZeroSHA, New: pushed},
+	} {
+		tree, err := NewServerTree(update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tree.Close()
+
+		if revision := tree.ManifestRevision(); revision != trusted {
+			t.Errorf("ManifestRevision() of %s = %s, want the trusted commit %s", update.Ref, revision, trusted)
+		}
+
+		manifest, err := tree.LoadManifest("", PreReceiveName)
+		if err != nil {
+			t.Fatalf("LoadManifest() error = %v", err)
+		}
+
+		if hooks := manifest.Hooks(PreReceiveName); len(hooks) != 1 {
+			t.Errorf("LoadManifest() of %s loaded the manifest of the pushed commit", update.Ref)
+		}
+	}
+
+	// a manifest of the repository that runs commands is rejected
+	writeTestFile(t, DefaultManifestFileName, "pre-receive:\n- run:\n  - run: make\n")
+	runGit(t, "commit", "-q", "-am", "command")
+	old := strings.TrimSpace(runGit(t, "rev-parse", "HEAD"))
+	tree, err := NewServerTree(&RefUpdate{Ref: "refs/heads/main", Old: old, New: pushed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	if _, err := tree.LoadManifest("", PreReceiveName); err == nil {
+		t.Error("LoadManifest() error = nil, want an error for the command")
+	}
+}
+
+func TestParseReceiveInput(t *testing.T) {
+	input := "1111 2222 refs/heads/main\n\ninvalid\n" + ZeroSHA + " 3333 refs/tags/v1\n"
+
+	want := []*RefUpdate{
+		{Old: "1111", New: "2222", Ref: "refs/heads/main"},
+		{Old: ZeroSHA, New: "3333", Ref: "refs/tags/v1"},
+	}
+	if got := ParseReceiveInput(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseReceiveInput() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseUpdateArgs(t *testing.T) {
+	want := []*RefUpdate{{Ref: "refs/heads/main", Old: "1111", New: "2222"}}
+	if got := ParseUpdateArgs([]string{"refs/heads/main", "1111", "2222"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseUpdateArgs() = %+v, want %+v", got, want)
+	}
+
+	if got := ParseUpdateArgs([]string{"refs/heads/main"}); len(got) != 0 {
+		t.Errorf("ParseUpdateArgs() = %+v, want no updates", got)
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// SlowResults returns the results that push the total duration over the threshold,
+// starting with the slowest ones.
+func SlowResults(results []*Result, threshold time.Duration) []*Result {
+	slow := []*Result{}
+	if threshold <= 0 {
+		return slow
+	}
+
+	total := time.Duration(0)
+	ran := []*Result{}
+	for _, result := range results {
+		if result.Ran() {
+			total += result.Duration
+			ran = append(ran, result)
+		}
+	}
+
+	sort.SliceStable(ran, func(i, j int) bool { return ran[i].Duration > ran[j].Duration })
+	for _, result := range ran {
+		if total <= threshold {
+			break
+		}
+
+		slow = append(slow, result)
+		total -= result.Duration
+	}
+
+	return slow
+}
+
+// WriteSummary writes a table with the status and duration of every command.
+func WriteSummary(writer io.Writer, results []*Result, total time.Duration, threshold time.Duration) {
+	slow := map[*Result]bool{}
+	for _, result := range SlowResults(results, threshold) {
+		slow[result] = true
+	}
+
+	table := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "HOOK\tCOMMAND\tSTATUS\tDURATION")
+	for _, result := range results {
+		duration := "-"
+		if result.Ran() {
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+		if slow[result] {
+			duration += " (slow)"
+		}
+
+		fmt.Fprintf(table, "%s %s\t%s\t%s\t%s\n", result.HookName, resultHookLabel(result), result.Command, result.Status, duration)
+	}
+	fmt.Fprintf(table, "TOTAL\t\t\t%s\n", total.Round(time.Millisecond))
+	table.Flush()
+
+	if threshold > 0 && total > threshold {
+		fmt.Fprintf(writer, "Warning: the hooks took %s, more than the %s budget set by warn_slower_than\n",
+			total.Round(time.Millisecond), threshold)
+	}
+}